@@ -32,8 +32,9 @@ var (
 	Core Application
 )
 
-func (a *Application) addCommand(ip string, xml string, headers map[string]string, response string, code int, err error) {
+func (a *Application) addCommand(ip string, xml string, headers map[string]string, response string, code int, attempt int, err error) {
 	var tmp CommandInfo
+	tmp.Attempt = attempt
 	tmp.RequestURL = ip
 	tmp.RequestBody = strings.Replace(xml, "\"", "'", -1)
 	tmp.RequestHeaders = headers
@@ -42,7 +43,9 @@ func (a *Application) addCommand(ip string, xml string, headers map[string]strin
 	if err != nil {
 		tmp.ResponseError = err.Error()
 	}
+	a.commandLock.Lock()
 	a.Commands = append(a.Commands, tmp)
+	a.commandLock.Unlock()
 }
 
 func (a *Application) createBlankConfig(filename string) {
@@ -111,6 +114,17 @@ func (a *Application) getReportDates(month, year string) (string, string) {
 	}
 	return month, year
 }
+
+func (a *Application) getReportRange(from, to string) bool {
+	start, end := functions.GetTimestampRange(from, to)
+	if start == 0 || end == 0 {
+		return false
+	}
+	a.Report.Start = start
+	a.Report.End = end
+	a.Report.Label = time.Unix(start, 0).Format("20060102") + "-" + time.Unix(end, 0).Format("20060102")
+	return true
+}
 func (a *Application) init() {
 	a.Config = viper.New()
 	a.Logger = logrus.New()
@@ -230,12 +244,32 @@ func (a *Application) LogWarn(message string, fields map[string]interface{}, war
 	}
 }
 
+func (a *Application) processUCSPMSystems() []interface{} {
+	var items []interface{}
+	var item map[string]interface{}
+	for i := 0; i < len(a.UCSPM.Systems); i++ {
+
+		item = make(map[string]interface{})
+		for key, value := range a.UCSPM.Systems[i].options {
+			item[key] = value
+		}
+		item["url"] = a.UCSPM.Systems[i].ip
+		item["username"] = a.UCSPM.Systems[i].username
+		item["password"] = a.UCSPM.Systems[i].password
+		items = append(items, item)
+	}
+	return items
+}
+
 func (a *Application) processSystems() []interface{} {
 	var items []interface{}
 	var item map[string]interface{}
 	for i := 0; i < len(a.UCS.Systems); i++ {
 
 		item = make(map[string]interface{})
+		for key, value := range a.UCS.Systems[i].options {
+			item[key] = value
+		}
 		item["url"] = a.UCS.Systems[i].ip
 		item["username"] = a.UCS.Systems[i].username
 		item["password"] = a.UCS.Systems[i].password
@@ -257,7 +291,21 @@ func (a *Application) saveConfig() {
 		items := a.processSystems()
 		a.Config.Set("ucs.systems", items)
 	}
-	out, err := yaml.Marshal(a.Config.AllSettings())
+	// Setting ucspm does not hide the legacy ucspm.url entry read from the file,
+	// so the section is replaced in a copy of the settings before writing.
+	settings := a.Config.AllSettings()
+	if len(a.UCSPM.Systems) > 0 || a.Config.IsSet("ucspm") {
+		ucspm := make(map[string]interface{})
+		for key, value := range a.Config.GetStringMap("ucspm") {
+			if key != "url" && key != "username" && key != "password" {
+				ucspm[key] = value
+			}
+		}
+		ucspm["systems"] = a.processUCSPMSystems()
+		a.Config.Set("ucspm", ucspm)
+		settings["ucspm"] = ucspm
+	}
+	out, err := yaml.Marshal(settings)
 	if err == nil {
 		fp, err := os.Create(a.ConfigFile)
 		if err == nil {
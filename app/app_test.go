@@ -0,0 +1,56 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/robjporter/go-functions/logrus"
+	"github.com/robjporter/go-functions/viper"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newTestApplication(configFile string) *Application {
+	a := &Application{ConfigFile: configFile}
+	a.Logger = logrus.New()
+	a.Logger.Out = ioutil.Discard
+	a.Config = viper.New()
+	a.Config.SetConfigName("config")
+	a.Config.SetConfigType("yaml")
+	a.Config.AddConfigPath(filepath.Dir(configFile))
+	return a
+}
+
+func loadTestApplication(configFile string) *Application {
+	a := newTestApplication(configFile)
+	if err := a.Config.ReadInConfig(); err != nil {
+		panic(err)
+	}
+	a.indexConfig()
+	return a
+}
+
+func Test_SaveConfigMigratesLegacyUCSPM(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "ucsm")
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "config.yaml")
+	ioutil.WriteFile(configFile, []byte("ucspm:\n  url: 10.0.0.1\n  username: admin\n  password: secret\n"), 0600)
+
+	Convey("Should read the legacy entry as one system", t, func() {
+		a := loadTestApplication(configFile)
+		So(len(a.UCSPM.Systems), ShouldEqual, 1)
+		So(a.Status.ucspmCount, ShouldEqual, 1)
+	})
+	Convey("Should keep one system across save and load cycles", t, func() {
+		for i := 0; i < 3; i++ {
+			loadTestApplication(configFile).saveConfig()
+		}
+		a := loadTestApplication(configFile)
+		So(len(a.UCSPM.Systems), ShouldEqual, 1)
+		So(a.Status.ucspmCount, ShouldEqual, 1)
+		So(a.UCSPM.Systems[0].ip, ShouldEqual, "10.0.0.1")
+		So(a.UCSPM.Systems[0].username, ShouldEqual, "admin")
+		So(a.Config.GetString("ucspm.url"), ShouldEqual, "")
+	})
+}
@@ -0,0 +1,171 @@
+package app
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/robjporter/go-functions/as"
+)
+
+const (
+	billingStatusCharged = "charged"
+	billingStatusFailed  = "not charged: report failed"
+	billingStatusNoData  = "not charged: no datapoints"
+)
+
+func (a *Application) billingInit() {
+	a.Billing = BillingInfo{}
+	a.Billing.Models = make(map[string]float64)
+	if !a.Config.IsSet("billing") {
+		a.LogInfo("No billing configuration found, charges will not be calculated.", nil, false)
+		return
+	}
+	a.Billing.Enabled = true
+	a.Billing.Currency = a.Config.GetString("billing.currency")
+	a.Billing.BaseFee = a.Config.GetFloat64("billing.basefee")
+	a.Billing.CoreFee = a.Config.GetFloat64("billing.corefee")
+	a.Billing.Basis = strings.ToLower(a.Config.GetString("billing.basis"))
+	if a.Billing.Basis == "" {
+		a.Billing.Basis = "mean"
+	}
+	if !billingValidBasis(a.Billing.Basis) {
+		a.LogFatal("Unknown billing basis, use mean, weighted, max or a percentile such as p95.", map[string]interface{}{"Basis": a.Billing.Basis})
+	}
+	models := a.Config.GetStringMap("billing.models")
+	for model, price := range models {
+		a.Billing.Models[strings.ToLower(strings.TrimSpace(model))] = as.ToFloat(price)
+	}
+	tiers := as.ToSlice(a.Config.Get("billing.tiers"))
+	for i := 0; i < len(tiers); i++ {
+		tier := as.ToStringMap(tiers[i])
+		var tmp BillingTier
+		tmp.from = as.ToFloat(tier["from"])
+		tmp.to = as.ToFloat(tier["to"])
+		tmp.price = as.ToFloat(tier["price"])
+		a.Billing.Tiers = append(a.Billing.Tiers, tmp)
+	}
+	a.LogInfo("Loaded billing configuration.", map[string]interface{}{"Currency": a.Billing.Currency, "BaseFee": a.Billing.BaseFee, "CoreFee": a.Billing.CoreFee, "Basis": a.Billing.Basis, "Models": len(a.Billing.Models), "Tiers": len(a.Billing.Tiers)}, false)
+}
+
+func billingValidBasis(basis string) bool {
+	switch basis {
+	case "mean", "weighted", "weightedmean", "max":
+		return true
+	}
+	if strings.HasPrefix(basis, "p") {
+		p, err := strconv.ParseFloat(basis[1:], 64)
+		return err == nil && p > 0 && p <= 100
+	}
+	return false
+}
+
+// billingProcess charges every server that has report data. Servers whose
+// report request failed, or that returned no datapoints, are not charged and
+// are marked in the billing summary instead.
+func (a *Application) billingProcess() {
+	a.billingInit()
+	if !a.Billing.Enabled {
+		return
+	}
+	a.LogInfo("Calculating charges for all matched servers.", map[string]interface{}{"Servers": len(a.Results)}, false)
+	failed := make(map[string]bool)
+	for i := 0; i < len(a.UCSPM.Failures); i++ {
+		failed[a.UCSPM.Failures[i].ucspmSystem+"|"+a.UCSPM.Failures[i].uid] = true
+	}
+	for i := 0; i < len(a.Results); i++ {
+		if failed[a.Results[i].ucspmSystem+"|"+a.Results[i].ucspmUID] {
+			a.Results[i].charge = BillingCharge{status: billingStatusFailed}
+		} else if a.Results[i].stats.datapoints == 0 {
+			a.Results[i].charge = BillingCharge{status: billingStatusNoData}
+		} else {
+			a.Results[i].charge = a.billingCalculateCharge(a.Results[i])
+			continue
+		}
+		a.LogWarn("Not charging server without report data.", map[string]interface{}{"Name": a.Results[i].ucspmName, "UID": a.Results[i].ucspmUID, "Status": a.Results[i].charge.status}, false)
+	}
+}
+
+func (a *Application) billingCalculateCharge(sys CombinedResults) BillingCharge {
+	var charge BillingCharge
+	charge.datapoints = sys.stats.datapoints
+	charge.averageCPU = a.billingUtilisation(sys)
+	charge.baseFee = a.Billing.BaseFee
+	charge.modelFee = a.billingModelPrice(sys.ucsModel, sys.ucsPID)
+	charge.coreFee = a.Billing.CoreFee * float64(sys.ucsCPUEnabledCores)
+	charge.tier, charge.tierFee = a.billingTierPrice(charge.averageCPU)
+	if charge.tier == "" && len(a.Billing.Tiers) > 0 {
+		a.LogWarn("Average CPU utilisation does not fall into any billing tier.", map[string]interface{}{"Name": sys.ucspmName, "UID": sys.ucspmUID, "Average": charge.averageCPU}, false)
+	}
+	charge.status = billingStatusCharged
+	charge.total = charge.baseFee + charge.modelFee + charge.coreFee + charge.tierFee
+	return charge
+}
+
+func (a *Application) billingUtilisation(sys CombinedResults) float64 {
+	switch a.Billing.Basis {
+	case "weighted", "weightedmean":
+		return sys.stats.weightedMean
+	case "p95":
+		return sys.stats.p95
+	case "p99":
+		return sys.stats.p99
+	case "max":
+		return sys.stats.max
+	}
+	if strings.HasPrefix(a.Billing.Basis, "p") {
+		if p, err := strconv.ParseFloat(a.Billing.Basis[1:], 64); err == nil {
+			return sys.reportData.series().Percentile(p)
+		}
+	}
+	return sys.stats.mean
+}
+
+func (a *Application) billingModelPrice(model string, pid string) float64 {
+	if price, ok := a.Billing.Models[strings.ToLower(strings.TrimSpace(model))]; ok && model != "" {
+		return price
+	}
+	if price, ok := a.Billing.Models[strings.ToLower(strings.TrimSpace(pid))]; ok && pid != "" {
+		return price
+	}
+	return 0
+}
+
+// billingTierPrice finds the band that average falls into. Bands include
+// their lower bound but not their upper one, so that a value on the boundary
+// of two bands falls into the higher, except that the highest band includes
+// its upper bound.
+func (a *Application) billingTierPrice(average float64) (string, float64) {
+	top := math.Inf(-1)
+	for i := 0; i < len(a.Billing.Tiers); i++ {
+		top = math.Max(top, a.Billing.Tiers[i].to)
+	}
+	for i := 0; i < len(a.Billing.Tiers); i++ {
+		if average >= a.Billing.Tiers[i].from && (average < a.Billing.Tiers[i].to || average == a.Billing.Tiers[i].to && average == top) {
+			return formatFloat(a.Billing.Tiers[i].from) + "-" + formatFloat(a.Billing.Tiers[i].to), a.Billing.Tiers[i].price
+		}
+	}
+	return "", 0
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+func (a *Application) billingExportToCSV() {
+	if !a.Billing.Enabled {
+		return
+	}
+	a.LogInfo("Saving billing summary.", map[string]interface{}{"Servers": len(a.Results)}, false)
+	total := 0.0
+	csv := "name,serial,model,pid,domain,serviceprofile,org,costcentre,enabledcores,datapoints," + a.Billing.Basis + "cpu,tier,basefee,modelfee,corefee,tierfee,charge,currency,status\n"
+	for i := 0; i < len(a.Results); i++ {
+		charge := a.Results[i].charge
+		total += charge.total
+		csv += a.Results[i].ucspmName + "," + a.Results[i].ucsSerial + "," + a.Results[i].ucsModel + "," + a.Results[i].ucsPID + ","
+		csv += a.Results[i].ucsSystem + "," + a.Results[i].ucsServiceProfile + "," + a.Results[i].ucsOrg + "," + a.Results[i].costCentre + "," + as.ToString(a.Results[i].ucsCPUEnabledCores) + "," + as.ToString(charge.datapoints) + "," + formatFloat(charge.averageCPU) + "," + charge.tier + ","
+		csv += formatFloat(charge.baseFee) + "," + formatFloat(charge.modelFee) + "," + formatFloat(charge.coreFee) + "," + formatFloat(charge.tierFee) + "," + formatFloat(charge.total) + "," + a.Billing.Currency + "," + charge.status + "\n"
+	}
+	csv += "TOTAL,,,,,,,,,,,,,,,," + formatFloat(total) + "," + a.Billing.Currency + ",\n"
+	a.saveFile("Stage6-Billing-"+a.Report.Label+".csv", csv)
+}
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_BillingProcessFailures(t *testing.T) {
+	a := newTestApplication("config.yaml")
+	a.Config.Set("billing.basefee", 10)
+	uid := "/zport/dmd/Devices/Server/host1"
+	a.Results = []CombinedResults{
+		{ucspmUID: uid, ucspmSystem: "10.0.0.1", stats: ReportStats{datapoints: 10, mean: 50}},
+		{ucspmUID: uid, ucspmSystem: "10.0.0.2", stats: ReportStats{datapoints: 10, mean: 50}},
+	}
+	a.UCSPM.Failures = []UCSPMFailureInfo{{uid: uid, ucspmSystem: "10.0.0.1"}}
+	a.billingProcess()
+
+	Convey("Should not charge the server whose report failed", t, func() {
+		So(a.Results[0].charge.status, ShouldEqual, billingStatusFailed)
+	})
+	Convey("Should charge the server with the same UID on another UCSPM", t, func() {
+		So(a.Results[1].charge.status, ShouldEqual, billingStatusCharged)
+		So(a.Results[1].charge.total, ShouldEqual, 10)
+	})
+}
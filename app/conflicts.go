@@ -0,0 +1,91 @@
+package app
+
+import (
+	"strings"
+
+	"../matching"
+)
+
+const (
+	matchStrategyConflict = "conflict"
+)
+
+// ucsDetectConflicts finds the UUIDs that more than one UCS server, or more
+// than one UCS Performance Manager host, claims. They are removed from
+// matching, and so from billing, until an override settles which server or
+// device they belong to.
+func (a *Application) ucsDetectConflicts(unmatched []string, skip map[string]bool) {
+	a.UCS.Conflicts = nil
+	overrides := a.matchOverrides()
+	for j := 0; j < len(a.UCS.UUID); j++ {
+		uuid := a.UCS.UUID[j]
+		if skip[matching.Normalise(uuid)] {
+			continue
+		}
+		servers := a.UCS.serverIndex.Lookup(uuid)
+		candidates := make([]matching.Server, len(servers))
+		for k := 0; k < len(servers); k++ {
+			candidates[k] = ucsMatchServer(a.UCS.Matches[servers[k]])
+		}
+		devices := a.UCS.deviceIndex.Lookup(uuid)
+		reporters := make([]matching.Device, len(devices))
+		for k := 0; k < len(devices); k++ {
+			reporters[k] = ucspmMatchDevice(a.UCSPM.Devices[devices[k]])
+		}
+
+		claimed, reported, reason := matching.Conflict(uuid, candidates, reporters, overrides, a.matchStrategyEnabled)
+		if reason == "" {
+			continue
+		}
+		var tmp UUIDConflict
+		tmp.uuid = uuid
+		tmp.reason = reason
+		for k := 0; k < len(claimed); k++ {
+			tmp.servers = append(tmp.servers, a.UCS.Matches[servers[claimed[k]]])
+		}
+		for k := 0; k < len(reported); k++ {
+			tmp.devices = append(tmp.devices, a.UCSPM.Devices[devices[reported[k]]])
+		}
+		unmatched[j] = "REMOVE"
+		skip[matching.Normalise(uuid)] = true
+		a.UCS.Conflicts = append(a.UCS.Conflicts, tmp)
+		a.LogWarn("UUID conflict found, excluding it from billing until an override resolves it.", map[string]interface{}{"UUID": uuid, "Reason": tmp.reason, "Servers": len(tmp.servers), "Devices": len(tmp.devices)}, false)
+	}
+	a.LogInfo("Checked UUIDs for conflicts.", map[string]interface{}{"UUID": len(a.UCS.UUID), "Conflicts": len(a.UCS.Conflicts)}, false)
+}
+
+func (a *Application) conflictsJSON() string {
+	jsonStr := `[`
+	for i := 0; i < len(a.UCS.Conflicts); i++ {
+		jsonStr += "{"
+		jsonStr += `"UUID" : "` + a.UCS.Conflicts[i].uuid + `",`
+		jsonStr += `"Reason" : "` + a.UCS.Conflicts[i].reason + `",`
+		jsonStr += `"Servers" : [`
+		for j := 0; j < len(a.UCS.Conflicts[i].servers); j++ {
+			jsonStr += "{"
+			jsonStr += `"DN" : "` + a.UCS.Conflicts[i].servers[j].serverdn + `",`
+			jsonStr += `"Domain" : "` + a.UCS.Conflicts[i].servers[j].ucsname + `",`
+			jsonStr += `"Serial" : "` + a.UCS.Conflicts[i].servers[j].serverserial + `",`
+			jsonStr += `"ServiceProfile" : "` + a.UCS.Conflicts[i].servers[j].serviceprofile + `",`
+			jsonStr += `"UUID" : "` + a.UCS.Conflicts[i].servers[j].serveruuid + `",`
+			jsonStr += `"OriginalUUID" : "` + a.UCS.Conflicts[i].servers[j].serverouuid + `"`
+			jsonStr += "},"
+		}
+		jsonStr = strings.TrimRight(jsonStr, ",")
+		jsonStr += `],`
+		jsonStr += `"Devices" : [`
+		for j := 0; j < len(a.UCS.Conflicts[i].devices); j++ {
+			jsonStr += "{"
+			jsonStr += `"Name" : "` + a.UCS.Conflicts[i].devices[j].name + `",`
+			jsonStr += `"UID" : "` + a.UCS.Conflicts[i].devices[j].uid + `",`
+			jsonStr += `"UCSPM" : "` + a.UCS.Conflicts[i].devices[j].ucspmSystem + `"`
+			jsonStr += "},"
+		}
+		jsonStr = strings.TrimRight(jsonStr, ",")
+		jsonStr += `]`
+		jsonStr += "},"
+	}
+	jsonStr = strings.TrimRight(jsonStr, ",")
+	jsonStr += `]`
+	return jsonStr
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 	"strings"
 
+	"../functions"
+
 	"github.com/robjporter/go-functions/as"
 )
 
@@ -34,9 +36,11 @@ func (a *Application) addUCSPM(ip, username, password string) bool {
 	if ip != "" {
 		if username != "" {
 			if password != "" {
-				a.Config.Set("ucspm.url", ip)
-				a.Config.Set("ucspm.username", username)
-				a.Config.Set("ucspm.password", a.EncryptPassword(password))
+				tmp := UCSPMSystemInfo{}
+				tmp.ip = ip
+				tmp.username = username
+				tmp.password = a.EncryptPassword(password)
+				a.UCSPM.Systems = append(a.UCSPM.Systems, tmp)
 				return true
 			} else {
 				a.Log("The password for the UCS Performance Manager system cannot be blank.", nil, false)
@@ -55,9 +59,11 @@ func (a *Application) addUCSPMSystem(ip, username, password string) {
 		if a.addUCSPM(ip, username, password) {
 			a.saveConfig()
 			a.LogInfo("UCS Performance Manager system has been added successfully.", map[string]interface{}{"IP": ip, "Username": username}, false)
+		} else {
+			a.LogInfo("UCS Performance Manager system could not be added.", map[string]interface{}{"IP": ip, "Username": username}, false)
 		}
 	} else {
-		a.LogInfo("A UCS Performance Manager already exists in the config file.", map[string]interface{}{"IP": ip, "Username": username}, false)
+		a.LogInfo("A UCS Performance Manager system already exists in the config file.", map[string]interface{}{"IP": ip, "Username": username}, false)
 	}
 }
 
@@ -89,9 +95,12 @@ func (a *Application) checkUCSExists(ip string) bool {
 }
 
 func (a *Application) checkUCSPMExists(ip string) bool {
+	a.Log("Search for UCS Performance Manager system in config file", map[string]interface{}{"IP": ip}, true)
 	a.getAllSystems()
-	if a.Config.IsSet("ucspm.url") {
-		return true
+	for i := 0; i < len(a.UCSPM.Systems); i++ {
+		if strings.TrimSpace(a.UCSPM.Systems[i].ip) == strings.TrimSpace(ip) {
+			return true
+		}
 	}
 	return false
 }
@@ -140,14 +149,26 @@ func (a *Application) deleteUCSSystem(ip string) {
 	}
 }
 
-func (a *Application) deleteUCSPMSystem() {
-	a.getAllSystems()
-	if a.Config.IsSet("ucspm.url") {
-		a.Config.Set("ucspm", false)
-		a.saveConfig()
-		a.LogInfo("UCS Performance Manager system has been deleted successfully.", nil, true)
+func (a *Application) deleteUCSPM(ip string) bool {
+	for i := 0; i < len(a.UCSPM.Systems); i++ {
+		if a.UCSPM.Systems[i].ip == as.ToString(ip) {
+			a.UCSPM.Systems = append(a.UCSPM.Systems[:i], a.UCSPM.Systems[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Application) deleteUCSPMSystem(ip string) {
+	if a.checkUCSPMExists(ip) {
+		if a.deleteUCSPM(ip) {
+			a.saveConfig()
+			a.LogInfo("UCS Performance Manager system has been deleted successfully.", map[string]interface{}{"IP": ip}, true)
+		} else {
+			a.Log("UCS Performance Manager system could not be deleted.", map[string]interface{}{"IP": ip}, false)
+		}
 	} else {
-		a.LogInfo("UCS Performance Manager system does not exsists and so cannot be deleted.", nil, false)
+		a.LogInfo("UCS Performance Manager system does not exsists and so cannot be deleted.", map[string]interface{}{"IP": ip}, false)
 	}
 }
 
@@ -155,6 +176,9 @@ func (a *Application) getAllSystems() {
 	tmp := as.ToSlice(a.Config.Get("ucs.systems"))
 	a.Log("Located UCS Systems in the config file", map[string]interface{}{"Systems": len(tmp)}, true)
 	a.readSystems(tmp)
+	tmp = as.ToSlice(a.Config.Get("ucspm.systems"))
+	a.Log("Located UCS Performance Manager systems in the config file", map[string]interface{}{"Systems": len(tmp)}, true)
+	a.readUCSPMSystems(tmp)
 }
 
 func (a *Application) getAllUCSSystemsCount() int {
@@ -163,11 +187,11 @@ func (a *Application) getAllUCSSystemsCount() int {
 }
 
 func (a *Application) getAllUCSPMSystemsCount() int {
-	if a.Config.IsSet("ucspm.url") {
-		return 1
-	} else {
-		return 0
+	tmp := as.ToSlice(a.Config.Get("ucspm.systems"))
+	if a.legacyUCSPMSystem(tmp) {
+		return len(tmp) + 1
 	}
+	return len(tmp)
 }
 
 func (a *Application) getEULAStatus() bool {
@@ -184,7 +208,8 @@ func (a *Application) processResponse(response string) {
 	a.addToCountMetrics(splits[0])
 	switch splits[0] {
 	case "RUN":
-		a.runAll(splits[1], splits[2])
+		a.reportQueryInit(splits[5], splits[6], splits[7], splits[8], splits[9])
+		a.runAll(splits[1], splits[2], splits[3], splits[4])
 	case "CLEAN":
 		a.cleanAll()
 	case "ADDUCS":
@@ -202,13 +227,15 @@ func (a *Application) processResponse(response string) {
 	case "UPDATEUCSPM":
 		a.updateUCSPMSystem(splits[1], splits[2], splits[3])
 	case "DELETEUCSPM":
-		a.deleteUCSPMSystem()
+		a.deleteUCSPMSystem(splits[1])
 	case "SHOWUCSPM":
-		a.showUCSPMSystem()
+		a.showUCSPMSystem(splits[1])
 	case "SETINPUT":
 		a.setInputFileName(splits[1])
 	case "SETOUTPUT":
 		a.setOutputFileName(splits[1])
+	case "SETPROXY":
+		a.setProxy(splits[1], splits[2], splits[3], splits[4])
 	case "DEBUG":
 		a.debug()
 	case "SHOWDEBUG":
@@ -225,18 +252,85 @@ func (a *Application) readSystems(ucss []interface{}) bool {
 		tmp.ip = newlist["url"]
 		tmp.username = newlist["username"]
 		tmp.password = newlist["password"]
+		tmp.options = systemOptions(newlist)
 		a.UCS.Systems = append(a.UCS.Systems, tmp)
 	}
 
 	return true
 }
 
-func (a *Application) runAll(month, year string) {
-	a.Log("Running inventory processes.", map[string]interface{}{"Month": month, "Year": year}, true)
-	month, year = a.getReportDates(month, year)
-	a.Log("Processed report dates.", map[string]interface{}{"Month": month, "Year": year}, true)
-	a.Report.Month = month
-	a.Report.Year = year
+// readUCSPMSystems also picks up the single ucspm.url entry written by earlier
+// versions, which is moved into ucspm.systems the next time the config is saved.
+func (a *Application) readUCSPMSystems(ucspms []interface{}) bool {
+	a.UCSPM.Systems = nil
+	for i := 0; i < len(ucspms); i++ {
+		var newlist map[string]string
+		newlist = as.ToStringMapString(ucspms[i])
+		tmp := UCSPMSystemInfo{}
+		tmp.ip = newlist["url"]
+		tmp.username = newlist["username"]
+		tmp.password = newlist["password"]
+		tmp.options = systemOptions(newlist)
+		a.UCSPM.Systems = append(a.UCSPM.Systems, tmp)
+	}
+	if a.legacyUCSPMSystem(ucspms) {
+		tmp := UCSPMSystemInfo{}
+		tmp.ip = a.Config.GetString("ucspm.url")
+		tmp.username = a.Config.GetString("ucspm.username")
+		tmp.password = a.Config.GetString("ucspm.password")
+		a.UCSPM.Systems = append(a.UCSPM.Systems, tmp)
+	}
+
+	return true
+}
+
+// legacyUCSPMSystem reports whether the config holds a ucspm.url entry that has
+// not already been moved into ucspm.systems.
+func (a *Application) legacyUCSPMSystem(ucspms []interface{}) bool {
+	url := a.Config.GetString("ucspm.url")
+	if url == "" {
+		return false
+	}
+	for i := 0; i < len(ucspms); i++ {
+		if as.ToStringMapString(ucspms[i])["url"] == url {
+			return false
+		}
+	}
+	return true
+}
+
+// systemOptions keeps any per-system settings other than the credentials, such
+// as TLS options, so they survive the config file being saved again.
+func systemOptions(system map[string]string) map[string]string {
+	options := make(map[string]string)
+	for key, value := range system {
+		if key != "url" && key != "username" && key != "password" {
+			options[key] = value
+		}
+	}
+	return options
+}
+
+func (a *Application) runAll(month, year, from, to string) {
+	a.Log("Running inventory processes.", map[string]interface{}{"Month": month, "Year": year, "From": from, "To": to}, true)
+	if (from != "" || to != "") && (month != "" || year != "") {
+		a.Log("The report date range cannot be combined with a month or year.", map[string]interface{}{"Month": month, "Year": year, "From": from, "To": to}, false)
+		return
+	}
+	if from != "" || to != "" {
+		if !a.getReportRange(from, to) {
+			a.Log("The report date range could not be processed.", map[string]interface{}{"From": from, "To": to}, false)
+			return
+		}
+	} else {
+		month, year = a.getReportDates(month, year)
+		a.Report.Month = month
+		a.Report.Year = year
+		a.Report.Start = functions.GetTimestampStartOfMonth(month, int(as.ToInt(year)))
+		a.Report.End = functions.GetTimestampEndOfMonth(month, int(as.ToInt(year)))
+		a.Report.Label = month + "-" + year
+	}
+	a.Log("Processed report dates.", map[string]interface{}{"Start": a.Report.Start, "End": a.Report.End, "Label": a.Report.Label}, true)
 	a.RunStage1()
 }
 
@@ -256,6 +350,51 @@ func (a *Application) setOutputFileName(filename string) {
 	a.saveConfig()
 }
 
+// setProxy sets the proxy for one system, or for every system when ip is
+// empty. The password is stored encrypted, like the system passwords.
+func (a *Application) setProxy(ip, url, username, password string) {
+	if password != "" {
+		password = a.EncryptPassword(password)
+	}
+	if ip == "" {
+		a.Config.Set("proxy.url", url)
+		a.Config.Set("proxy.username", username)
+		a.Config.Set("proxy.password", password)
+		a.saveConfig()
+		a.LogInfo("Proxy has been set for every system.", map[string]interface{}{"Proxy": redactProxy(url), "Username": username}, false)
+		return
+	}
+	found := false
+	for i := 0; i < len(a.UCS.Systems); i++ {
+		if a.UCS.Systems[i].ip == ip {
+			a.UCS.Systems[i].options = proxyOptions(a.UCS.Systems[i].options, url, username, password)
+			found = true
+		}
+	}
+	for i := 0; i < len(a.UCSPM.Systems); i++ {
+		if a.UCSPM.Systems[i].ip == ip {
+			a.UCSPM.Systems[i].options = proxyOptions(a.UCSPM.Systems[i].options, url, username, password)
+			found = true
+		}
+	}
+	if !found {
+		a.Log("The system does not exist and so its proxy cannot be set.", map[string]interface{}{"URL": ip}, false)
+		return
+	}
+	a.saveConfig()
+	a.LogInfo("Proxy has been set for the system.", map[string]interface{}{"URL": ip, "Proxy": redactProxy(url), "Username": username}, false)
+}
+
+func proxyOptions(options map[string]string, url, username, password string) map[string]string {
+	if options == nil {
+		options = make(map[string]string)
+	}
+	options["proxy"] = url
+	options["proxyusername"] = username
+	options["proxypassword"] = password
+	return options
+}
+
 func (a *Application) showUCS(ip string) {
 	for i := 0; i < len(a.UCS.Systems); i++ {
 		if a.UCS.Systems[i].ip == as.ToString(ip) {
@@ -281,14 +420,30 @@ func (a *Application) showUCSSystems() {
 		a.LogInfo("UCS Domain", map[string]interface{}{"Username": a.UCS.Systems[i].username}, false)
 		a.LogInfo("UCS Domain", map[string]interface{}{"Password": a.UCS.Systems[i].password}, false)
 	}
-	a.showUCSPMSystem()
+	a.showUCSPMSystems()
+}
+
+func (a *Application) showUCSPM(ip string) {
+	for i := 0; i < len(a.UCSPM.Systems); i++ {
+		if a.UCSPM.Systems[i].ip == as.ToString(ip) {
+			a.LogInfo("UCS Performance Manager", map[string]interface{}{"URL": a.UCSPM.Systems[i].ip}, false)
+			a.LogInfo("UCS Performance Manager", map[string]interface{}{"Username": a.UCSPM.Systems[i].username}, false)
+			a.LogInfo("UCS Performance Manager", map[string]interface{}{"Password": a.UCSPM.Systems[i].password}, false)
+		}
+	}
 }
 
-func (a *Application) showUCSPMSystem() {
-	if a.Config.IsSet("ucspm.url") {
-		a.LogInfo("UCS Performance Manager", map[string]interface{}{"URL": a.Config.GetString("ucspm.url")}, false)
-		a.LogInfo("UCS Performance Manager", map[string]interface{}{"Username": a.Config.GetString("ucspm.username")}, false)
-		a.LogInfo("UCS Performance Manager", map[string]interface{}{"Password": a.Config.GetString("ucspm.password")}, false)
+func (a *Application) showUCSPMSystem(ip string) {
+	if a.checkUCSPMExists(ip) {
+		a.showUCSPM(ip)
+	} else {
+		a.Log("The UCS Performance Manager system does not exist and so cannot be displayed.", map[string]interface{}{"URL": ip}, false)
+	}
+}
+
+func (a *Application) showUCSPMSystems() {
+	for i := 0; i < len(a.UCSPM.Systems); i++ {
+		a.showUCSPM(a.UCSPM.Systems[i].ip)
 	}
 }
 
@@ -315,11 +470,23 @@ func (a *Application) updateUCSSystem(ip, username, password string) {
 	}
 }
 
+func (a *Application) updateUCSPM(ip, username, password string) bool {
+	for i := 0; i < len(a.UCSPM.Systems); i++ {
+		if a.UCSPM.Systems[i].ip == as.ToString(ip) {
+			a.UCSPM.Systems[i].username = username
+			a.UCSPM.Systems[i].password = a.EncryptPassword(password)
+		}
+	}
+	return true
+}
+
 func (a *Application) updateUCSPMSystem(ip, username, password string) {
 	if a.checkUCSPMExists(ip) {
-		if a.addUCSPM(ip, username, password) {
+		if a.updateUCSPM(ip, username, password) {
 			a.saveConfig()
 			a.LogInfo("UCS Performance Manager system has been updated successfully.", map[string]interface{}{"IP": ip, "Username": username}, false)
+		} else {
+			a.LogInfo("UCS Performance Manager system could not be updated.", map[string]interface{}{"IP": ip, "Username": username}, false)
 		}
 	} else {
 		a.LogInfo("A UCS Performance Manager instance does not exist in the config file.", map[string]interface{}{"IP": ip, "Username": username}, false)
@@ -0,0 +1,21 @@
+package app
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_RunAllRejectsMonthWithRange(t *testing.T) {
+	Convey("Should not run when --from is combined with --month", t, func() {
+		a := newTestApplication("config.yaml")
+		a.runAll("feb", "", "2017-02-01", "")
+		So(a.Report.Start, ShouldEqual, 0)
+		So(a.Report.Label, ShouldEqual, "")
+	})
+	Convey("Should not run when --to is combined with --year", t, func() {
+		a := newTestApplication("config.yaml")
+		a.runAll("", "2017", "", "2017-02-07")
+		So(a.Report.Start, ShouldEqual, 0)
+	})
+}
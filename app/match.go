@@ -0,0 +1,298 @@
+package app
+
+import (
+	"sort"
+	"strings"
+
+	"../matching"
+)
+
+const (
+	matchStrategyUUID         = matching.StrategyUUID
+	matchStrategyOriginalUUID = matching.StrategyOriginalUUID
+	matchStrategyTransposed   = matching.StrategyTransposed
+	matchStrategySerial       = matching.StrategySerial
+	matchStrategyHostname     = matching.StrategyHostname
+	matchStrategyNone         = "unmatched"
+
+	matchConfidenceHigh   = "high"
+	matchConfidenceMedium = "medium"
+	matchConfidenceLow    = "low"
+)
+
+var defaultMatchStrategies = []string{matchStrategyUUID, matchStrategyOriginalUUID, matchStrategyTransposed, matchStrategySerial, matchStrategyHostname}
+
+// matchConfidence is how far a match by the given strategy can be trusted.
+// UUIDs identify the server directly, the transposed UUID and the serial
+// reported by vSphere are one step removed, and a hostname only matches a
+// service profile by naming convention.
+func matchConfidence(strategy string) string {
+	switch strategy {
+	case matchStrategyUUID, matchStrategyOriginalUUID:
+		return matchConfidenceHigh
+	case matchStrategyTransposed, matchStrategySerial:
+		return matchConfidenceMedium
+	case matchStrategyHostname:
+		return matchConfidenceLow
+	case matchStrategyOverride:
+		return matchConfidenceManual
+	}
+	return ""
+}
+
+func (a *Application) ucsLoadMatchStrategies() {
+	a.UCS.Strategies = defaultMatchStrategies
+	if a.Config.IsSet("match.strategies") {
+		strategies := []string{}
+		configured := a.Config.GetStringSlice("match.strategies")
+		for i := 0; i < len(configured); i++ {
+			strategy := strings.ToLower(strings.TrimSpace(configured[i]))
+			if !inStringSlice(defaultMatchStrategies, strategy) {
+				a.LogWarn("Ignoring unknown match strategy.", map[string]interface{}{"Strategy": configured[i]}, false)
+			} else if !inStringSlice(strategies, strategy) {
+				strategies = append(strategies, strategy)
+			}
+		}
+		a.UCS.Strategies = strategies
+	}
+	a.LogInfo("Loaded UUID match strategies.", map[string]interface{}{"Strategies": a.UCS.Strategies}, false)
+}
+
+func (a *Application) matchStrategyEnabled(strategy string) bool {
+	return inStringSlice(a.UCS.Strategies, strategy)
+}
+
+// ucsMatchStrategy returns how a UCS server matches a UUID reported by UCS
+// Performance Manager, or an empty string if it does not.
+func (a *Application) ucsMatchStrategy(mat UCSSystemMatchInfo, uuid string) string {
+	return matching.Strategy(ucsMatchServer(mat), uuid, a.matchStrategyEnabled)
+}
+
+func ucsMatchServer(mat UCSSystemMatchInfo) matching.Server {
+	return matching.Server{UUID: mat.serveruuid, OriginalUUID: mat.serverouuid, Serial: mat.serverserial, Profile: mat.serviceprofile, Domain: mat.ucsname, Claimed: mat.matchstrategy != ""}
+}
+
+func (a *Application) ucsMatchServers() []matching.Server {
+	servers := make([]matching.Server, len(a.UCS.Matches))
+	for i := 0; i < len(a.UCS.Matches); i++ {
+		servers[i] = ucsMatchServer(a.UCS.Matches[i])
+	}
+	return servers
+}
+
+func ucspmMatchDevice(dev UCSPMDeviceInfo) matching.Device {
+	return matching.Device{UID: dev.uid, UUID: dev.uuid, Host: ucspmDeviceHost(dev), Serial: dev.serial, Ignore: dev.ignore}
+}
+
+func (a *Application) ucspmMatchDevices() []matching.Device {
+	devices := make([]matching.Device, len(a.UCSPM.Devices))
+	for i := 0; i < len(a.UCSPM.Devices); i++ {
+		devices[i] = ucspmMatchDevice(a.UCSPM.Devices[i])
+	}
+	return devices
+}
+
+// ucsBuildMatchIndexes indexes the UUID list, the servers by every UUID form,
+// serial and service profile, and the devices by UUID, so that matching does
+// not compare every server with every UUID.
+func (a *Application) ucsBuildMatchIndexes() {
+	a.UCS.uuidIndex = matching.IndexList(a.UCS.UUID)
+	a.UCS.serverIndex = matching.NewIndex()
+	a.UCS.serialIndex = matching.NewIndex()
+	a.UCS.profileIndex = matching.NewIndex()
+	for i := 0; i < len(a.UCS.Matches); i++ {
+		a.UCS.serverIndex.Add(a.UCS.Matches[i].serveruuid, i)
+		a.UCS.serverIndex.Add(a.UCS.Matches[i].serverouuid, i)
+		a.UCS.serverIndex.Add(matching.Transpose(matching.Normalise(a.UCS.Matches[i].serveruuid)), i)
+		a.UCS.serialIndex.Add(a.UCS.Matches[i].serverserial, i)
+		a.UCS.profileIndex.Add(matching.ShortName(a.UCS.Matches[i].serviceprofile), i)
+	}
+	a.UCS.deviceIndex = a.ucspmIndexDevices()
+}
+
+func (a *Application) ucsIndexMatched() {
+	a.UCS.matchedIndex = matching.NewIndex()
+	for i := 0; i < len(a.UCS.Matched); i++ {
+		a.UCS.matchedIndex.Add(a.UCS.Matched[i].matcheduuid, i)
+	}
+}
+
+func (a *Application) ucspmIndexDevices() matching.Index {
+	index := matching.NewIndex()
+	for i := 0; i < len(a.UCSPM.Devices); i++ {
+		index.Add(a.UCSPM.Devices[i].uuid, i)
+	}
+	return index
+}
+
+// ucsFallbackServers returns, in order, the servers whose serial or service
+// profile could tie them to dev.
+func (a *Application) ucsFallbackServers(dev UCSPMDeviceInfo) []int {
+	servers := []int{}
+	if a.matchStrategyEnabled(matchStrategySerial) {
+		servers = append(servers, a.UCS.serialIndex.Lookup(dev.serial)...)
+	}
+	if a.matchStrategyEnabled(matchStrategyHostname) {
+		servers = append(servers, a.UCS.profileIndex.Lookup(ucspmDeviceHost(dev))...)
+	}
+	return uniquePositions(servers)
+}
+
+func uniquePositions(positions []int) []int {
+	sorted := append([]int{}, positions...)
+	sort.Ints(sorted)
+	unique := []int{}
+	for i := 0; i < len(sorted); i++ {
+		if i == 0 || sorted[i] != sorted[i-1] {
+			unique = append(unique, sorted[i])
+		}
+	}
+	return unique
+}
+
+// ucsFallbackStrategy returns the first enabled fallback strategy that ties a
+// UCS server to a UCS Performance Manager device, or an empty string.
+func (a *Application) ucsFallbackStrategy(mat UCSSystemMatchInfo, dev UCSPMDeviceInfo) string {
+	return matching.FallbackStrategy(ucsMatchServer(mat), ucspmMatchDevice(dev), a.UCS.Strategies)
+}
+
+// ucspmDeviceHost is the short host name of a device, as reported by the
+// hypervisor where possible.
+func ucspmDeviceHost(dev UCSPMDeviceInfo) string {
+	host := matching.ShortName(dev.hypervisorName)
+	if host == "" {
+		host = matching.ShortName(dev.name)
+	}
+	return host
+}
+
+// ucsProcessFallbackMatches tries the serial and hostname strategies for the
+// UUIDs that no UCS server claimed, using servers that are not yet matched. A
+// UUID that fits more than one server is a conflict rather than a match.
+func (a *Application) ucsProcessFallbackMatches() {
+	if !a.matchStrategyEnabled(matchStrategySerial) && !a.matchStrategyEnabled(matchStrategyHostname) {
+		return
+	}
+	unmatched := []string{}
+	count := 0
+	overrides := a.matchOverrides()
+	servers := a.ucsMatchServers()
+	for i := 0; i < len(a.UCS.Unmatched); i++ {
+		uuid := a.UCS.Unmatched[i]
+		dev, ok := a.ucspmGetDeviceByUUID(uuid)
+		if !ok {
+			unmatched = append(unmatched, uuid)
+			continue
+		}
+		allowed := func(server matching.Server) bool { return matching.Allows(overrides, server, uuid) }
+		fits, reason := matching.Fallback(servers, a.ucsFallbackServers(dev), ucspmMatchDevice(dev), a.UCS.Strategies, allowed)
+		if len(fits) == 0 {
+			unmatched = append(unmatched, uuid)
+		} else if reason != "" {
+			var tmp UUIDConflict
+			tmp.uuid = uuid
+			tmp.reason = reason
+			tmp.devices = append(tmp.devices, dev)
+			for k := 0; k < len(fits); k++ {
+				tmp.servers = append(tmp.servers, a.UCS.Matches[fits[k]])
+			}
+			a.UCS.Conflicts = append(a.UCS.Conflicts, tmp)
+			a.LogWarn("UUID conflict found, excluding it from billing until an override resolves it.", map[string]interface{}{"UUID": tmp.uuid, "Reason": tmp.reason, "Servers": len(tmp.servers), "Devices": len(tmp.devices)}, false)
+		} else {
+			j := fits[0]
+			strategy := a.ucsFallbackStrategy(a.UCS.Matches[j], dev)
+			a.UCS.Matches[j].matcheduuid = uuid
+			a.UCS.Matches[j].matchstrategy = strategy
+			a.UCS.Matches[j].matchconfidence = matchConfidence(strategy)
+			a.UCS.Matched = append(a.UCS.Matched, a.UCS.Matches[j])
+			servers[j].Claimed = true
+			a.Log("Matched UUID using fallback strategy.", map[string]interface{}{"UUID": uuid, "Device": dev.name, "DN": a.UCS.Matches[j].serverdn, "Strategy": strategy}, true)
+			count++
+		}
+	}
+	a.UCS.Unmatched = unmatched
+	a.LogInfo("Completed fallback UUID matching.", map[string]interface{}{"Matched": count, "Unmatched": len(a.UCS.Unmatched)}, false)
+}
+
+func (a *Application) ucspmGetDeviceByUUID(uuid string) (UCSPMDeviceInfo, bool) {
+	if a.UCS.deviceIndex == nil {
+		a.UCS.deviceIndex = a.ucspmIndexDevices()
+	}
+	devices := a.UCS.deviceIndex.Lookup(uuid)
+	for i := 0; i < len(devices); i++ {
+		if !a.UCSPM.Devices[devices[i]].ignore {
+			return a.UCSPM.Devices[devices[i]], true
+		}
+	}
+	return UCSPMDeviceInfo{}, false
+}
+
+func (a *Application) ucsBuildMatchAudit() {
+	a.UCS.Audit = nil
+	for i := 0; i < len(a.UCS.Matched); i++ {
+		var tmp MatchAuditInfo
+		tmp.ucspmuuid = a.UCS.Matched[i].matcheduuid
+		tmp.strategy = a.UCS.Matched[i].matchstrategy
+		tmp.confidence = a.UCS.Matched[i].matchconfidence
+		tmp.serveruuid = a.UCS.Matched[i].serveruuid
+		tmp.serverouuid = a.UCS.Matched[i].serverouuid
+		tmp.transposed = matching.Transpose(a.UCS.Matched[i].serveruuid)
+		tmp.serverdn = a.UCS.Matched[i].serverdn
+		tmp.ucsname = a.UCS.Matched[i].ucsname
+		tmp.candidates = a.ucsMatchCandidates(tmp.ucspmuuid, a.UCS.Matched[i])
+		a.UCS.Audit = append(a.UCS.Audit, tmp)
+	}
+	for i := 0; i < len(a.UCS.Unmatched); i++ {
+		var tmp MatchAuditInfo
+		tmp.ucspmuuid = a.UCS.Unmatched[i]
+		tmp.strategy = matchStrategyNone
+		a.UCS.Audit = append(a.UCS.Audit, tmp)
+	}
+	for i := 0; i < len(a.UCS.Excluded); i++ {
+		var tmp MatchAuditInfo
+		tmp.ucspmuuid = a.UCS.Excluded[i]
+		tmp.strategy = matchStrategyExcluded
+		tmp.confidence = matchConfidenceManual
+		a.UCS.Audit = append(a.UCS.Audit, tmp)
+	}
+	for i := 0; i < len(a.UCS.Conflicts); i++ {
+		var tmp MatchAuditInfo
+		tmp.ucspmuuid = a.UCS.Conflicts[i].uuid
+		tmp.strategy = matchStrategyConflict
+		for j := 0; j < len(a.UCS.Conflicts[i].servers); j++ {
+			server := a.UCS.Conflicts[i].servers[j]
+			strategy := a.ucsMatchStrategy(server, tmp.ucspmuuid)
+			if strategy == "" && len(a.UCS.Conflicts[i].devices) > 0 {
+				strategy = a.ucsFallbackStrategy(server, a.UCS.Conflicts[i].devices[0])
+			}
+			tmp.candidates = append(tmp.candidates, MatchCandidate{serverdn: server.serverdn, serveruuid: server.serveruuid, ucsname: server.ucsname, strategy: strategy, confidence: matchConfidence(strategy)})
+		}
+		a.UCS.Audit = append(a.UCS.Audit, tmp)
+	}
+	a.LogInfo("Built UUID match audit.", map[string]interface{}{"Matched": len(a.UCS.Matched), "Unmatched": len(a.UCS.Unmatched), "Conflicts": len(a.UCS.Conflicts)}, false)
+}
+
+// ucsMatchCandidates lists the other UCS servers that would also have matched
+// uuid, which is what needs checking when a match is disputed.
+func (a *Application) ucsMatchCandidates(uuid string, chosen UCSSystemMatchInfo) []MatchCandidate {
+	candidates := []MatchCandidate{}
+	dev, hasDevice := a.ucspmGetDeviceByUUID(uuid)
+	servers := a.UCS.serverIndex.Lookup(uuid)
+	if hasDevice {
+		servers = uniquePositions(append(a.ucsFallbackServers(dev), servers...))
+	}
+	for k := 0; k < len(servers); k++ {
+		i := servers[k]
+		if a.UCS.Matches[i].serverdn == chosen.serverdn && a.UCS.Matches[i].ucsip == chosen.ucsip {
+			continue
+		}
+		strategy := a.ucsMatchStrategy(a.UCS.Matches[i], uuid)
+		if strategy == "" && hasDevice {
+			strategy = a.ucsFallbackStrategy(a.UCS.Matches[i], dev)
+		}
+		if strategy != "" {
+			candidates = append(candidates, MatchCandidate{serverdn: a.UCS.Matches[i].serverdn, serveruuid: a.UCS.Matches[i].serveruuid, ucsname: a.UCS.Matches[i].ucsname, strategy: strategy, confidence: matchConfidence(strategy)})
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"../matching"
+	"github.com/robjporter/go-functions/as"
+	"github.com/robjporter/go-functions/viper"
+)
+
+const (
+	matchStrategyOverride = "override"
+	matchStrategyExcluded = "excluded"
+	matchConfidenceManual = "manual"
+)
+
+// ucsLoadOverrides reads the overrides file named by match.overrides. A
+// relative path is taken from the directory holding the config file.
+func (a *Application) ucsLoadOverrides() {
+	a.UCS.Overrides = nil
+	filename := a.Config.GetString("match.overrides")
+	if filename == "" {
+		return
+	}
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(filepath.Dir(a.ConfigFile), filename)
+	}
+	if _, err := os.Stat(filename); err != nil {
+		a.LogWarn("Unable to find the match overrides file.", map[string]interface{}{"Filename": filename, "Error": err}, false)
+		return
+	}
+
+	overrides := viper.New()
+	overrides.SetConfigType(strings.TrimPrefix(filepath.Ext(filename), "."))
+	overrides.SetConfigName(strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)))
+	overrides.AddConfigPath(filepath.Dir(filename))
+	if err := overrides.ReadInConfig(); err != nil {
+		a.LogWarn("Unable to read the match overrides file.", map[string]interface{}{"Filename": filename, "Error": err}, false)
+		return
+	}
+
+	entries := as.ToSlice(overrides.Get("overrides"))
+	for i := 0; i < len(entries); i++ {
+		entry := as.ToStringMapString(entries[i])
+		tmp := MatchOverride{}
+		tmp.uuid = strings.TrimSpace(entry["uuid"])
+		tmp.uid = strings.TrimSpace(entry["uid"])
+		tmp.serial = strings.TrimSpace(entry["serial"])
+		tmp.domain = strings.TrimSpace(entry["domain"])
+		tmp.costcentre = strings.TrimSpace(entry["costcentre"])
+		tmp.exclude, _ = strconv.ParseBool(entry["exclude"])
+		if tmp.uuid == "" && tmp.uid == "" {
+			a.LogWarn("Ignoring match override without a uuid or uid.", map[string]interface{}{"Entry": i + 1}, false)
+			continue
+		}
+		a.UCS.Overrides = append(a.UCS.Overrides, tmp)
+	}
+	a.LogInfo("Loaded match overrides.", map[string]interface{}{"Filename": filename, "Overrides": len(a.UCS.Overrides)}, false)
+}
+
+// ucsApplyOverrides runs before the automatic strategies. Excluded UUIDs and
+// UUIDs pinned to a serial are removed from unmatched and returned, keyed by
+// their normalised form, so that nothing else can claim them.
+func (a *Application) ucsApplyOverrides(unmatched []string) map[string]bool {
+	overridden := make(map[string]bool)
+	overrides := a.matchOverrides()
+	devices := a.ucspmMatchDevices()
+	servers := a.ucsMatchServers()
+	for i := 0; i < len(a.UCS.Overrides); i++ {
+		override := &a.UCS.Overrides[i]
+		result := matching.Resolve(overrides, i, a.UCS.UUID, a.UCS.uuidIndex, devices, servers)
+		override.resolved = result.UUID
+		override.status = result.Status
+		overrides[i].Resolved = result.UUID
+
+		switch result.Status {
+		case matching.OverrideNoDevice:
+			a.LogWarn("Match override does not match any device.", map[string]interface{}{"UUID": override.uuid, "UID": override.uid}, false)
+		case matching.OverrideNoServer:
+			a.LogWarn("Match override does not match any server.", map[string]interface{}{"UUID": override.resolved, "Serial": override.serial, "Domain": override.domain}, false)
+		}
+		if !result.Remove {
+			continue
+		}
+
+		unmatched[result.Position] = "REMOVE"
+		overridden[matching.Normalise(result.UUID)] = true
+		if result.Server < 0 {
+			a.UCS.Excluded = append(a.UCS.Excluded, result.UUID)
+			continue
+		}
+		a.UCS.Matches[result.Server].matcheduuid = result.UUID
+		a.UCS.Matches[result.Server].matchstrategy = matchStrategyOverride
+		a.UCS.Matches[result.Server].matchconfidence = matchConfidenceManual
+		a.UCS.Matched = append(a.UCS.Matched, a.UCS.Matches[result.Server])
+		servers[result.Server].Claimed = true
+	}
+	return overridden
+}
+
+func (a *Application) matchOverrides() []matching.Override {
+	overrides := make([]matching.Override, len(a.UCS.Overrides))
+	for i := 0; i < len(a.UCS.Overrides); i++ {
+		overrides[i] = matching.Override{UUID: a.UCS.Overrides[i].uuid, UID: a.UCS.Overrides[i].uid, Serial: a.UCS.Overrides[i].serial, Domain: a.UCS.Overrides[i].domain, Exclude: a.UCS.Overrides[i].exclude, Resolved: a.UCS.Overrides[i].resolved}
+	}
+	return overrides
+}
+
+// ucsOverrideAllows reports whether the automatic strategies may match uuid to
+// a server, which an override limits to the server's domain. overrides comes
+// from matchOverrides, built once by the caller.
+func ucsOverrideAllows(overrides []matching.Override, mat UCSSystemMatchInfo, uuid string) bool {
+	return matching.Allows(overrides, ucsMatchServer(mat), uuid)
+}
+
+// ucspmOverrideForDevice prefers an override for the device's UID over one for
+// its UUID. overrides comes from matchOverrides, built once by the caller.
+func (a *Application) ucspmOverrideForDevice(overrides []matching.Override, dev UCSPMDeviceInfo) *MatchOverride {
+	i := matching.ForDevice(overrides, ucspmMatchDevice(dev))
+	if i < 0 {
+		return nil
+	}
+	return &a.UCS.Overrides[i]
+}
@@ -74,6 +74,9 @@ func (a *Application) saveRunStage5() {
 
 	a.saveUUIDS()
 	a.saveIgnored()
+	a.saveMatchAudit()
+	a.saveOverrides()
+	a.saveConflicts()
 }
 
 func (a *Application) saveUUIDS() {
@@ -91,6 +94,15 @@ func (a *Application) saveUUIDS() {
 		jsonStr += `"PID" : "` + a.UCS.Matches[i].serverpid + `",`
 		jsonStr += `"MODEL" : "` + a.UCS.Matches[i].servermodel + `",`
 		jsonStr += `"SERIAL" : "` + a.UCS.Matches[i].serverserial + `",`
+		jsonStr += `"ASSIGNEDTO" : "` + a.UCS.Matches[i].serverassigned + `",`
+		jsonStr += `"SERVICEPROFILE" : "` + a.UCS.Matches[i].serviceprofile + `",`
+		jsonStr += `"ORG" : "` + a.UCS.Matches[i].serviceorg + `",`
+		jsonStr += `"CPUMODEL" : "` + a.UCS.Matches[i].cpumodel + `",`
+		jsonStr += `"CPUSPEED" : "` + a.UCS.Matches[i].cpuspeed + `",`
+		jsonStr += `"SOCKETS" : "` + as.ToString(a.UCS.Matches[i].cpusockets) + `",`
+		jsonStr += `"CORES" : "` + as.ToString(a.UCS.Matches[i].cpucores) + `",`
+		jsonStr += `"ENABLEDCORES" : "` + as.ToString(a.UCS.Matches[i].cpuenabled) + `",`
+		jsonStr += `"THREADS" : "` + as.ToString(a.UCS.Matches[i].cputhreads) + `",`
 		jsonStr += `"DOMAINNAME" : "` + a.UCS.Matches[i].ucsname + `",`
 		jsonStr += `"DOMAINVERSION" : "` + a.UCS.Matches[i].ucsversion + `",`
 		jsonStr += `"DOMAINURL" : "` + a.UCS.Matches[i].ucsip + `"`
@@ -119,6 +131,7 @@ func (a *Application) saveIgnored() {
 			jsonStr += `"model":"` + as.ToString(a.UCSPM.Devices[i].model) + `",`
 			jsonStr += `"name":"` + as.ToString(a.UCSPM.Devices[i].name) + `",`
 			jsonStr += `"ucspmName":"` + as.ToString(a.UCSPM.Devices[i].ucspmName) + `",`
+			jsonStr += `"ucspmSystem":"` + as.ToString(a.UCSPM.Devices[i].ucspmSystem) + `",`
 			jsonStr += `"uid":"` + as.ToString(a.UCSPM.Devices[i].uid) + `",`
 			jsonStr += `"uuid":"` + as.ToString(a.UCSPM.Devices[i].uuid) + `"`
 			jsonStr += "},"
@@ -131,6 +144,77 @@ func (a *Application) saveIgnored() {
 	a.saveFile("Stage5-IgnoredDevices.json", jsonStr)
 }
 
+func (a *Application) saveMatchAudit() {
+	a.LogInfo("Saving UUID match audit.", map[string]interface{}{"Entries": len(a.UCS.Audit)}, false)
+	index := a.ucspmIndexDevices()
+	jsonStr := `{"Matches": [`
+	for i := 0; i < len(a.UCS.Audit); i++ {
+		devices := []string{}
+		positions := index.Lookup(a.UCS.Audit[i].ucspmuuid)
+		for j := 0; j < len(positions); j++ {
+			if !inStringSlice(devices, a.UCSPM.Devices[positions[j]].name) {
+				devices = append(devices, a.UCSPM.Devices[positions[j]].name)
+			}
+		}
+		jsonStr += "{"
+		jsonStr += `"UCSPMUUID" : "` + a.UCS.Audit[i].ucspmuuid + `",`
+		jsonStr += `"UCSPMDevices" : "` + strings.Join(devices, ";") + `",`
+		jsonStr += `"Strategy" : "` + a.UCS.Audit[i].strategy + `",`
+		jsonStr += `"Confidence" : "` + a.UCS.Audit[i].confidence + `",`
+		jsonStr += `"ServerUUID" : "` + a.UCS.Audit[i].serveruuid + `",`
+		jsonStr += `"ServerOriginalUUID" : "` + a.UCS.Audit[i].serverouuid + `",`
+		jsonStr += `"ServerTransposedUUID" : "` + a.UCS.Audit[i].transposed + `",`
+		jsonStr += `"DN" : "` + a.UCS.Audit[i].serverdn + `",`
+		jsonStr += `"Domain" : "` + a.UCS.Audit[i].ucsname + `",`
+		jsonStr += `"Candidates" : [`
+		for j := 0; j < len(a.UCS.Audit[i].candidates); j++ {
+			jsonStr += "{"
+			jsonStr += `"DN" : "` + a.UCS.Audit[i].candidates[j].serverdn + `",`
+			jsonStr += `"UUID" : "` + a.UCS.Audit[i].candidates[j].serveruuid + `",`
+			jsonStr += `"Domain" : "` + a.UCS.Audit[i].candidates[j].ucsname + `",`
+			jsonStr += `"Strategy" : "` + a.UCS.Audit[i].candidates[j].strategy + `",`
+			jsonStr += `"Confidence" : "` + a.UCS.Audit[i].candidates[j].confidence + `"`
+			jsonStr += "},"
+		}
+		jsonStr = strings.TrimRight(jsonStr, ",")
+		jsonStr += "]"
+		jsonStr += "},"
+	}
+	jsonStr = strings.TrimRight(jsonStr, ",")
+	jsonStr += `]}`
+
+	a.saveFile("Stage5-MatchAudit.json", jsonStr)
+}
+
+func (a *Application) saveOverrides() {
+	if len(a.UCS.Overrides) == 0 {
+		return
+	}
+	a.LogInfo("Saving match overrides.", map[string]interface{}{"Overrides": len(a.UCS.Overrides)}, false)
+	jsonStr := `{"Overrides": [`
+	for i := 0; i < len(a.UCS.Overrides); i++ {
+		jsonStr += "{"
+		jsonStr += `"UUID" : "` + a.UCS.Overrides[i].uuid + `",`
+		jsonStr += `"UID" : "` + a.UCS.Overrides[i].uid + `",`
+		jsonStr += `"ResolvedUUID" : "` + a.UCS.Overrides[i].resolved + `",`
+		jsonStr += `"Serial" : "` + a.UCS.Overrides[i].serial + `",`
+		jsonStr += `"Domain" : "` + a.UCS.Overrides[i].domain + `",`
+		jsonStr += `"CostCentre" : "` + a.UCS.Overrides[i].costcentre + `",`
+		jsonStr += `"Exclude" : "` + as.ToString(a.UCS.Overrides[i].exclude) + `",`
+		jsonStr += `"Status" : "` + a.UCS.Overrides[i].status + `"`
+		jsonStr += "},"
+	}
+	jsonStr = strings.TrimRight(jsonStr, ",")
+	jsonStr += `]}`
+
+	a.saveFile("Stage5-Overrides.json", jsonStr)
+}
+
+func (a *Application) saveConflicts() {
+	a.LogInfo("Saving UUID conflicts.", map[string]interface{}{"Conflicts": len(a.UCS.Conflicts)}, false)
+	a.saveFile("Stage5-Conflicts.json", `{"Conflicts": `+a.conflictsJSON()+`}`)
+}
+
 func (a *Application) saveRunStage6() {
 	a.LogInfo("Saving data from Run Stage 6.", nil, false)
 
@@ -146,18 +230,41 @@ func (a *Application) saveRunStage6() {
 		jsonStr += `"System" : "` + a.Results[i].ucsSystem + `",`
 		jsonStr += `"Position" : "` + a.Results[i].ucsPosition + `",`
 		jsonStr += `"DN" : "` + a.Results[i].ucsDN + `",`
+		jsonStr += `"ServiceProfile" : "` + a.Results[i].ucsServiceProfile + `",`
+		jsonStr += `"Org" : "` + a.Results[i].ucsOrg + `",`
+		jsonStr += `"CostCentre" : "` + a.Results[i].costCentre + `",`
+		jsonStr += `"CPUModel" : "` + a.Results[i].ucsCPUModel + `",`
+		jsonStr += `"Sockets" : "` + as.ToString(a.Results[i].ucsCPUSockets) + `",`
+		jsonStr += `"Cores" : "` + as.ToString(a.Results[i].ucsCPUCores) + `",`
+		jsonStr += `"EnabledCores" : "` + as.ToString(a.Results[i].ucsCPUEnabledCores) + `",`
+		jsonStr += `"Threads" : "` + as.ToString(a.Results[i].ucsCPUThreads) + `",`
+		jsonStr += `"MatchStrategy" : "` + a.Results[i].ucsMatchStrategy + `",`
+		jsonStr += `"MatchConfidence" : "` + a.Results[i].ucsMatchConfidence + `",`
 		jsonStr += `"IsManaged" : "` + as.ToString(a.Results[i].isManaged) + `",`
 		jsonStr += `"Name2" : "` + a.Results[i].ucspmName + `",`
 		jsonStr += `"UID" : "` + a.Results[i].ucspmUID + `",`
+		jsonStr += `"UCSPM" : "` + a.Results[i].ucspmSystem + `",`
 		jsonStr += `"Key" : "` + a.Results[i].ucspmKey + `",`
-		jsonStr += `"UUID" : "` + a.Results[i].ucspmUUID + `"`
+		jsonStr += `"UUID" : "` + a.Results[i].ucspmUUID + `",`
+		jsonStr += `"Datapoints" : "` + as.ToString(a.Results[i].stats.datapoints) + `",`
+		jsonStr += `"Mean" : "` + formatFloat(a.Results[i].stats.mean) + `",`
+		jsonStr += `"WeightedMean" : "` + formatFloat(a.Results[i].stats.weightedMean) + `",`
+		jsonStr += `"P95" : "` + formatFloat(a.Results[i].stats.p95) + `",`
+		jsonStr += `"P99" : "` + formatFloat(a.Results[i].stats.p99) + `",`
+		jsonStr += `"BurstSeconds" : "` + as.ToString(int64(a.Results[i].stats.burst.Seconds())) + `",`
+		jsonStr += `"CoreHours" : "` + formatFloat(a.Results[i].stats.coreHours) + `"`
 		jsonStr += "},"
 	}
 
 	jsonStr = strings.TrimRight(jsonStr, ",")
-	jsonStr += `]}`
+	jsonStr += `],`
+	jsonStr += `"Conflicts": ` + a.conflictsJSON()
+	jsonStr += `}`
 
 	a.saveFile("Stage6-MergedResults.json", jsonStr)
+	a.summaryExportToCSV()
+	a.billingExportToCSV()
+	a.saveReportFailures()
 
 	a.LogInfo("Successfully matched UUIDs.", map[string]interface{}{"Discovered": len(a.UCS.UUID), "Matched": len(a.UCS.Matched)}, true)
 	a.saveMatchedUUID()
@@ -167,6 +274,24 @@ func (a *Application) saveRunStage6() {
 	}
 }
 
+func (a *Application) saveReportFailures() {
+	a.LogInfo("Saving failed report requests.", map[string]interface{}{"Failures": len(a.UCSPM.Failures)}, false)
+	jsonStr := `{"Failures": [`
+	for i := 0; i < len(a.UCSPM.Failures); i++ {
+		jsonStr += "{"
+		jsonStr += `"UID" : "` + a.UCSPM.Failures[i].uid + `",`
+		jsonStr += `"Name" : "` + a.UCSPM.Failures[i].name + `",`
+		jsonStr += `"UCSPM" : "` + a.UCSPM.Failures[i].ucspmSystem + `",`
+		jsonStr += `"Error" : "` + strings.Replace(a.UCSPM.Failures[i].err, "\"", "'", -1) + `"`
+		jsonStr += "},"
+	}
+
+	jsonStr = strings.TrimRight(jsonStr, ",")
+	jsonStr += `]}`
+
+	a.saveFile("Stage6-ReportFailures.json", jsonStr)
+}
+
 func (a *Application) saveRunStage7() {
 	a.LogInfo("Saving data from Run Stage 7.", nil, false)
 	a.exportHTTPCommands()
@@ -180,6 +305,7 @@ func (a *Application) exportHTTPCommands() {
 
 	for i := 0; i < len(a.Commands); i++ {
 		jsonStr += "{"
+		jsonStr += `"Attempt" : "` + as.ToString(a.Commands[i].Attempt) + `",`
 		jsonStr += `"Request" : {`
 		jsonStr += `"URL" : "` + a.Commands[i].RequestURL + `",`
 		jsonStr += `"Headers" : "` + as.ToString(a.Commands[i].RequestHeaders) + `",`
@@ -201,27 +327,29 @@ func (a *Application) exportHTTPCommands() {
 
 func (a *Application) saveMatchedUUID() {
 	a.LogInfo("Saving unmatched UUID.", map[string]interface{}{"Unmatched": len(a.UCS.Unmatched)}, false)
-	found := false
+	devices := a.ucspmIndexDevices()
 	jsonStr := `{"UUIDS": [`
 	for i := 0; i < len(a.UCS.Matched); i++ {
-		for j := len(a.UCSPM.Devices) - 1; j > -1; j-- {
-			if a.UCSPM.Devices[j].uuid == a.UCS.Matched[i].serveruuid && !found {
-				jsonStr += "{"
-				jsonStr += `"hasHypervisor":"` + as.ToString(a.UCSPM.Devices[j].hasHypervisor) + `",`
-				jsonStr += `"hypervisorName":"` + as.ToString(a.UCSPM.Devices[j].hypervisorName) + `",`
-				jsonStr += `"hypervisorVersion":"` + as.ToString(a.UCSPM.Devices[j].hypervisorVersion) + `",`
-				jsonStr += `"ignore":"` + as.ToString(a.UCSPM.Devices[j].ignore) + `",`
-				jsonStr += `"isHypervisor":"` + as.ToString(a.UCSPM.Devices[j].ishypervisor) + `",`
-				jsonStr += `"model":"` + as.ToString(a.UCSPM.Devices[j].model) + `",`
-				jsonStr += `"name":"` + as.ToString(a.UCSPM.Devices[j].name) + `",`
-				jsonStr += `"ucspmName":"` + as.ToString(a.UCSPM.Devices[j].ucspmName) + `",`
-				jsonStr += `"uid":"` + as.ToString(a.UCSPM.Devices[j].uid) + `",`
-				jsonStr += `"uuid":"` + as.ToString(a.UCSPM.Devices[j].uuid) + `"`
-				jsonStr += "},"
-				found = true
-			}
+		if j := devices.Last(a.UCS.Matched[i].matcheduuid); j > -1 {
+			jsonStr += "{"
+			jsonStr += `"hasHypervisor":"` + as.ToString(a.UCSPM.Devices[j].hasHypervisor) + `",`
+			jsonStr += `"hypervisorName":"` + as.ToString(a.UCSPM.Devices[j].hypervisorName) + `",`
+			jsonStr += `"hypervisorVersion":"` + as.ToString(a.UCSPM.Devices[j].hypervisorVersion) + `",`
+			jsonStr += `"ignore":"` + as.ToString(a.UCSPM.Devices[j].ignore) + `",`
+			jsonStr += `"isHypervisor":"` + as.ToString(a.UCSPM.Devices[j].ishypervisor) + `",`
+			jsonStr += `"model":"` + as.ToString(a.UCSPM.Devices[j].model) + `",`
+			jsonStr += `"name":"` + as.ToString(a.UCSPM.Devices[j].name) + `",`
+			jsonStr += `"serviceProfile":"` + a.UCS.Matched[i].serviceprofile + `",`
+			jsonStr += `"org":"` + a.UCS.Matched[i].serviceorg + `",`
+			jsonStr += `"matchStrategy":"` + a.UCS.Matched[i].matchstrategy + `",`
+			jsonStr += `"matchConfidence":"` + a.UCS.Matched[i].matchconfidence + `",`
+			jsonStr += `"serverUuid":"` + a.UCS.Matched[i].serveruuid + `",`
+			jsonStr += `"ucspmName":"` + as.ToString(a.UCSPM.Devices[j].ucspmName) + `",`
+			jsonStr += `"ucspmSystem":"` + as.ToString(a.UCSPM.Devices[j].ucspmSystem) + `",`
+			jsonStr += `"uid":"` + as.ToString(a.UCSPM.Devices[j].uid) + `",`
+			jsonStr += `"uuid":"` + as.ToString(a.UCSPM.Devices[j].uuid) + `"`
+			jsonStr += "},"
 		}
-		found = false
 	}
 	jsonStr = strings.TrimRight(jsonStr, ",")
 	jsonStr += `]}`
@@ -231,25 +359,23 @@ func (a *Application) saveMatchedUUID() {
 
 func (a *Application) saveUnmatchedUUID() {
 	a.LogInfo("Saving unmatched UUID.", map[string]interface{}{"Unmatched": len(a.UCS.Unmatched)}, false)
-	found := false
+	devices := a.ucspmIndexDevices()
 	jsonStr := `{"UUIDS": [`
 	for i := 0; i < len(a.UCS.Unmatched); i++ {
-		for j := len(a.UCSPM.Devices) - 1; j > -1; j-- {
-			if a.UCSPM.Devices[j].uuid == a.UCS.Unmatched[i] && !found {
-				jsonStr += "{"
-				jsonStr += `"hasHypervisor":"` + as.ToString(a.UCSPM.Devices[j].hasHypervisor) + `",`
-				jsonStr += `"hypervisorName":"` + as.ToString(a.UCSPM.Devices[j].hypervisorName) + `",`
-				jsonStr += `"hypervisorVersion":"` + as.ToString(a.UCSPM.Devices[j].hypervisorVersion) + `",`
-				jsonStr += `"ignore":"` + as.ToString(a.UCSPM.Devices[j].ignore) + `",`
-				jsonStr += `"isHypervisor":"` + as.ToString(a.UCSPM.Devices[j].ishypervisor) + `",`
-				jsonStr += `"model":"` + as.ToString(a.UCSPM.Devices[j].model) + `",`
-				jsonStr += `"name":"` + as.ToString(a.UCSPM.Devices[j].name) + `",`
-				jsonStr += `"ucspmName":"` + as.ToString(a.UCSPM.Devices[j].ucspmName) + `",`
-				jsonStr += `"uid":"` + as.ToString(a.UCSPM.Devices[j].uid) + `",`
-				jsonStr += `"uuid":"` + as.ToString(a.UCSPM.Devices[j].uuid) + `"`
-				jsonStr += "},"
-				found = true
-			}
+		if j := devices.Last(a.UCS.Unmatched[i]); j > -1 {
+			jsonStr += "{"
+			jsonStr += `"hasHypervisor":"` + as.ToString(a.UCSPM.Devices[j].hasHypervisor) + `",`
+			jsonStr += `"hypervisorName":"` + as.ToString(a.UCSPM.Devices[j].hypervisorName) + `",`
+			jsonStr += `"hypervisorVersion":"` + as.ToString(a.UCSPM.Devices[j].hypervisorVersion) + `",`
+			jsonStr += `"ignore":"` + as.ToString(a.UCSPM.Devices[j].ignore) + `",`
+			jsonStr += `"isHypervisor":"` + as.ToString(a.UCSPM.Devices[j].ishypervisor) + `",`
+			jsonStr += `"model":"` + as.ToString(a.UCSPM.Devices[j].model) + `",`
+			jsonStr += `"name":"` + as.ToString(a.UCSPM.Devices[j].name) + `",`
+			jsonStr += `"ucspmName":"` + as.ToString(a.UCSPM.Devices[j].ucspmName) + `",`
+			jsonStr += `"ucspmSystem":"` + as.ToString(a.UCSPM.Devices[j].ucspmSystem) + `",`
+			jsonStr += `"uid":"` + as.ToString(a.UCSPM.Devices[j].uid) + `",`
+			jsonStr += `"uuid":"` + as.ToString(a.UCSPM.Devices[j].uuid) + `"`
+			jsonStr += "},"
 		}
 	}
 	jsonStr = strings.TrimRight(jsonStr, ",")
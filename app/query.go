@@ -0,0 +1,143 @@
+package app
+
+import (
+	"sort"
+	"strings"
+
+	"../ucspm"
+)
+
+const (
+	reportDefaultDownsample = "1h-avg"
+	reportDefaultAggregator = "avg"
+	reportDefaultMetric     = "cpuUsage_cpuUsage"
+	reportDefaultExpression = "rpn:Usage-raw,100,/"
+	reportCPUMetric         = "cpu"
+)
+
+func (a *Application) reportQueryInit(customer, downsample, aggregator, metric, expression string) {
+	query := ReportQuery{
+		Downsample: reportDefaultDownsample,
+		Aggregator: reportDefaultAggregator,
+		Metric:     reportDefaultMetric,
+		Expression: reportDefaultExpression,
+	}
+	query = a.reportQueryFromConfig("report", query)
+	if customer != "" {
+		key := "report.customers." + strings.ToLower(customer)
+		if a.Config.IsSet(key) {
+			query = a.reportQueryFromConfig(key, query)
+		} else {
+			a.LogWarn("No report settings found for customer, using the defaults.", map[string]interface{}{"Customer": customer}, false)
+		}
+	}
+	query = reportQueryOverride(query, downsample, aggregator, metric, expression)
+	a.Report.Customer = customer
+	a.Report.Query = query
+	a.Log("Report query defined.", map[string]interface{}{"Customer": customer, "Downsample": query.Downsample, "Aggregator": query.Aggregator, "Metric": query.Metric, "Expression": query.Expression}, true)
+}
+
+func (a *Application) reportQueryFromConfig(key string, query ReportQuery) ReportQuery {
+	if a.Config.IsSet(key + ".metrics") {
+		query.Extra = reportMetricsFromMap(a.Config.GetStringMapString(key + ".metrics"))
+	}
+	return reportQueryOverride(query,
+		a.Config.GetString(key+".downsample"),
+		a.Config.GetString(key+".aggregator"),
+		a.Config.GetString(key+".metric"),
+		a.Config.GetString(key+".expression"))
+}
+
+// reportMetricsFromMap turns the name to metric map from the config file into
+// a list sorted by name, so the output columns are in a stable order.
+func reportMetricsFromMap(metrics map[string]string) []ReportMetric {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		if strings.ToLower(name) != reportCPUMetric && strings.TrimSpace(metrics[name]) != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	result := []ReportMetric{}
+	for _, name := range names {
+		result = append(result, ReportMetric{Name: strings.ToLower(name), Metric: strings.TrimSpace(metrics[name])})
+	}
+	return result
+}
+
+func (q ReportQuery) metricNames() []string {
+	names := []string{reportCPUMetric}
+	for _, metric := range q.Extra {
+		names = append(names, metric.Name)
+	}
+	return names
+}
+
+// metricName maps the series name in a performance query response back to the
+// name it is stored under.
+func (q ReportQuery) metricName(series string) string {
+	if series == "Usage" || series == "Usage-raw" {
+		return reportCPUMetric
+	}
+	for _, metric := range q.Extra {
+		if metric.Name == series {
+			return metric.Name
+		}
+	}
+	return ""
+}
+
+func reportQueryOverride(query ReportQuery, downsample, aggregator, metric, expression string) ReportQuery {
+	if strings.TrimSpace(downsample) != "" {
+		query.Downsample = strings.TrimSpace(downsample)
+	}
+	if strings.TrimSpace(aggregator) != "" {
+		query.Aggregator = strings.TrimSpace(aggregator)
+	}
+	if strings.TrimSpace(metric) != "" {
+		query.Metric = strings.TrimSpace(metric)
+	}
+	if strings.TrimSpace(expression) != "" {
+		query.Expression = strings.TrimSpace(expression)
+	}
+	return query
+}
+
+// ucspmBuildReportQuery builds the api/performance/query/ request. Setting the
+// expression to "none" returns the raw metric rather than the calculated one.
+func (a *Application) ucspmBuildReportQuery(sys CombinedResults) ucspm.PerformanceQuery {
+	query := a.Report.Query
+	raw := strings.ToLower(query.Expression) == "none"
+	tags := map[string][]string{"key": {sys.ucspmKey}}
+	request := ucspm.PerformanceQuery{
+		Start:      a.Report.Start,
+		End:        a.Report.End,
+		Series:     true,
+		Downsample: query.Downsample,
+		ReturnSet:  "EXACT",
+	}
+	request.Metrics = append(request.Metrics, ucspm.PerformanceMetric{
+		Metric:     sys.ucspmHypervisorName + "/" + query.Metric,
+		Aggregator: query.Aggregator,
+		Tags:       tags,
+		Name:       "Usage-raw",
+		Emit:       raw,
+	})
+	if !raw {
+		request.Metrics = append(request.Metrics, ucspm.PerformanceMetric{
+			Name:       "Usage",
+			Expression: query.Expression,
+			Emit:       true,
+		})
+	}
+	for _, metric := range query.Extra {
+		request.Metrics = append(request.Metrics, ucspm.PerformanceMetric{
+			Metric:     sys.ucspmHypervisorName + "/" + metric.Metric,
+			Aggregator: query.Aggregator,
+			Tags:       tags,
+			Name:       metric.Name,
+			Emit:       true,
+		})
+	}
+	return request
+}
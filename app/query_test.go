@@ -0,0 +1,22 @@
+package app
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_ReportQueryMetrics(t *testing.T) {
+	Convey("Should only request CPU usage by default", t, func() {
+		a := newTestApplication("config.yaml")
+		a.reportQueryInit("", "", "", "", "")
+		So(a.Report.Query.metricNames(), ShouldResemble, []string{"cpu"})
+		So(len(a.ucspmBuildReportQuery(CombinedResults{}).Metrics), ShouldEqual, 2)
+	})
+	Convey("Should add the metrics set in the config file", t, func() {
+		a := newTestApplication("config.yaml")
+		a.Config.Set("report.metrics", map[string]interface{}{"memory": "memConsumed_memConsumed"})
+		a.reportQueryInit("", "", "", "", "")
+		So(a.Report.Query.metricNames(), ShouldResemble, []string{"cpu", "memory"})
+	})
+}
@@ -39,7 +39,7 @@ func (a *Application) RunStage3() {
 	a.LogInfo("Entering Run stage 3 - Integrity Check", nil, false)
 	if a.Status.eula == true {
 		if a.Status.ucsCount > 1 {
-			if a.Status.ucspmCount == 1 {
+			if a.Status.ucspmCount > 0 {
 				a.LogInfo("All systems, config and checks completed successfully.", nil, false)
 				a.saveRunStage3()
 				a.RunStage4()
@@ -76,6 +76,8 @@ func (a *Application) RunStage5() {
 func (a *Application) RunStage6() {
 	a.LogInfo("Entering Run stage 6 - UCS Performance Manager Reports", nil, false)
 	a.ucspmProcessReports()
+	a.statsProcess()
+	a.billingProcess()
 	a.saveRunStage6()
 	a.RunStage7()
 }
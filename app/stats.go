@@ -0,0 +1,67 @@
+package app
+
+import (
+	"../stats"
+)
+
+const (
+	statsDefaultBurstThreshold = 80.0
+	statsDefaultCores          = 1
+)
+
+func (a *Application) statsProcess() {
+	threshold, cores := a.statsOptions()
+	a.LogInfo("Calculating utilisation statistics.", map[string]interface{}{"Servers": len(a.Results), "BurstThreshold": threshold, "Cores": cores}, false)
+	for i := 0; i < len(a.Results); i++ {
+		a.Results[i].stats = a.Results[i].reportData.Stats(threshold, statsCores(cores, a.Results[i]))
+	}
+}
+
+// statsOptions returns the burst threshold and the configured core count, which
+// is 0 when each server's own cores should be used.
+func (a *Application) statsOptions() (float64, int) {
+	threshold := statsDefaultBurstThreshold
+	if a.Config.IsSet("stats.burstthreshold") {
+		threshold = a.Config.GetFloat64("stats.burstthreshold")
+	}
+	cores := 0
+	if a.Config.GetInt("stats.cores") > 0 {
+		cores = a.Config.GetInt("stats.cores")
+	}
+	return threshold, cores
+}
+
+// statsCores returns the configured core count, or the server's enabled cores
+// from the UCS inventory when none is configured.
+func statsCores(configured int, sys CombinedResults) int {
+	if configured > 0 {
+		return configured
+	}
+	if sys.ucsCPUEnabledCores > 0 {
+		return sys.ucsCPUEnabledCores
+	}
+	return statsDefaultCores
+}
+
+func (d dataSlice) series() stats.Series {
+	series := make(stats.Series, len(d))
+	for i := 0; i < len(d); i++ {
+		series[i] = stats.Sample{Unix: d[i].unix, Value: d[i].value}
+	}
+	return series
+}
+
+func (d dataSlice) Stats(threshold float64, cores int) ReportStats {
+	series := d.series()
+	var result ReportStats
+	result.datapoints = len(series)
+	result.min = series.Min()
+	result.mean = series.Mean()
+	result.weightedMean = series.WeightedMean()
+	result.max = series.Max()
+	result.p95 = series.Percentile(95)
+	result.p99 = series.Percentile(99)
+	result.burst = series.BurstDuration(threshold)
+	result.coreHours = series.CoreHours(cores)
+	return result
+}
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_ReportStatsRows(t *testing.T) {
+	a := newTestApplication("config.yaml")
+	sys := CombinedResults{ucsCPUEnabledCores: 2}
+	metrics := map[string]dataSlice{
+		"cpu":    {{unix: 0, value: 10}, {unix: 300, value: 90}},
+		"memory": {{unix: 0, value: 40}, {unix: 300, value: 60}},
+	}
+	rows := a.reportStatsRows(sys, []string{"cpu", "memory"}, metrics)
+
+	Convey("Should summarise every column", t, func() {
+		So(rows, ShouldContainSubstring, "datapoints,2,2\n")
+		So(rows, ShouldContainSubstring, "mean,50.00,50.00\n")
+		So(rows, ShouldContainSubstring, "max,90.00,60.00\n")
+	})
+	Convey("Should only give the burst time and core-hours for CPU", t, func() {
+		So(rows, ShouldContainSubstring, "burstseconds,300,\n")
+		So(rows, ShouldContainSubstring, "corehours,0.17,\n")
+	})
+}
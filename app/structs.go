@@ -1,6 +1,13 @@
 package app
 
 import (
+	"sync"
+	"time"
+
+	"../matching"
+	"../transport"
+	"../ucsm"
+	"../ucspm"
 	"github.com/robjporter/go-functions/logrus"
 	"github.com/robjporter/go-functions/viper"
 )
@@ -9,27 +16,42 @@ type UCSSystemInfo struct {
 	ip       string
 	username string
 	password string
-	cookie   string
+	options  map[string]string
+	client   *ucsm.Client
+	session  *ucsm.SessionManager
 	name     string
 	version  string
 }
 
 type UCSSystemMatchInfo struct {
-	serverposition string
-	serverserial   string
-	serveruuid     string
-	servername     string
-	serverpid      string
-	serverdn       string
-	serverdescr    string
-	servermodel    string
-	serverouuid    string
-	ucsname        string
-	ucsversion     string
-	ucsip          string
+	serverposition  string
+	serverserial    string
+	serveruuid      string
+	servername      string
+	serverpid       string
+	serverdn        string
+	serverdescr     string
+	servermodel     string
+	serverouuid     string
+	serverassigned  string
+	serviceprofile  string
+	serviceorg      string
+	cpumodel        string
+	cpuspeed        string
+	cpusockets      int
+	cpucores        int
+	cpuenabled      int
+	cputhreads      int
+	matcheduuid     string
+	matchstrategy   string
+	matchconfidence string
+	ucsname         string
+	ucsversion      string
+	ucsip           string
 }
 
 type CommandInfo struct {
+	Attempt        int
 	RequestURL     string
 	RequestHeaders map[string]string
 	RequestBody    string
@@ -45,21 +67,129 @@ type UCSInfo struct {
 	Matches    []UCSSystemMatchInfo
 	Matched    []UCSSystemMatchInfo
 	Unmatched  []string
+	Audit      []MatchAuditInfo
+	Strategies []string
+	Overrides  []MatchOverride
+	Excluded   []string
+	Conflicts  []UUIDConflict
+
+	uuidIndex    matching.Index
+	serverIndex  matching.Index
+	serialIndex  matching.Index
+	profileIndex matching.Index
+	deviceIndex  matching.Index
+	matchedIndex matching.Index
+}
+
+type UUIDConflict struct {
+	uuid    string
+	reason  string
+	servers []UCSSystemMatchInfo
+	devices []UCSPMDeviceInfo
+}
+
+type MatchOverride struct {
+	uuid       string
+	uid        string
+	serial     string
+	domain     string
+	costcentre string
+	exclude    bool
+	resolved   string
+	status     string
+}
+
+type MatchAuditInfo struct {
+	ucspmuuid   string
+	strategy    string
+	confidence  string
+	serveruuid  string
+	serverouuid string
+	transposed  string
+	serverdn    string
+	ucsname     string
+	candidates  []MatchCandidate
+}
+
+type MatchCandidate struct {
+	serverdn   string
+	serveruuid string
+	ucsname    string
+	strategy   string
+	confidence string
+}
+
+type UCSPMSystemInfo struct {
+	ip       string
+	username string
+	password string
+	options  map[string]string
 }
 
 type UCSPMInfo struct {
-	Routers       map[string]string
-	TidCount      int
+	Clients       map[string]*ucspm.Client
+	Systems       []UCSPMSystemInfo
 	Devices       []UCSPMDeviceInfo
-	host          string
-	username      string
-	password      string
 	ProcessedUUID []string
+	Failures      []UCSPMFailureInfo
+}
+
+type UCSPMFailureInfo struct {
+	uid         string
+	name        string
+	ucspmSystem string
+	err         string
 }
 
 type ReportInfo struct {
-	Month string
-	Year  string
+	Month    string
+	Year     string
+	Start    int64
+	End      int64
+	Label    string
+	Customer string
+	Query    ReportQuery
+}
+
+type ReportQuery struct {
+	Downsample string
+	Aggregator string
+	Metric     string
+	Expression string
+	Extra      []ReportMetric
+}
+
+type ReportMetric struct {
+	Name   string
+	Metric string
+}
+
+type BillingInfo struct {
+	Enabled  bool
+	Currency string
+	Basis    string
+	BaseFee  float64
+	CoreFee  float64
+	Models   map[string]float64
+	Tiers    []BillingTier
+}
+
+type BillingTier struct {
+	from  float64
+	to    float64
+	price float64
+}
+
+type BillingCharge struct {
+	averageCPU float64
+	datapoints int
+	tier       string
+	baseFee    float64
+	modelFee   float64
+	coreFee    float64
+	tierFee    float64
+	total      float64
+	status     string
 }
 
 type AppStatus struct {
@@ -74,15 +204,31 @@ type CombinedResults struct {
 	ucspmKey            string
 	ucspmUUID           string
 	ucspmHypervisorName string
+	ucspmSystem         string
 	ucsName             string
 	ucsPosition         string
 	ucsSerial           string
 	ucsDN               string
 	ucsDesc             string
 	ucsModel            string
+	ucsPID              string
 	ucsSystem           string
+	ucsServiceProfile   string
+	ucsOrg              string
+	ucsCPUModel         string
+	ucsCPUSpeed         string
+	ucsCPUSockets       int
+	ucsCPUCores         int
+	ucsCPUEnabledCores  int
+	ucsCPUThreads       int
+	ucsMatchStrategy    string
+	ucsMatchConfidence  string
+	costCentre          string
 	isManaged           bool
-	reportData          []ReportData
+	reportData          dataSlice
+	metrics             map[string]dataSlice
+	stats               ReportStats
+	charge              BillingCharge
 }
 
 type Application struct {
@@ -94,6 +240,7 @@ type Application struct {
 	RunTimeStamp string
 	Key          []byte
 	Report       ReportInfo
+	Billing      BillingInfo
 	Status       AppStatus
 	UCSPM        UCSPMInfo
 	UCS          UCSInfo
@@ -101,6 +248,9 @@ type Application struct {
 	Action       string
 	Version      string
 	Commands     []CommandInfo
+	commandLock  sync.Mutex
+	breaker      *transport.Breaker
+	breakerOnce  sync.Once
 }
 
 type UCSPMDeviceInfo struct {
@@ -109,19 +259,34 @@ type UCSPMDeviceInfo struct {
 	ignore              bool
 	name                string
 	model               string
+	serial              string
 	ishypervisor        bool
 	hypervisorName      string
 	hypervisorVersion   string
 	hypervisorShortName string
 	ucspmName           string
 	hasHypervisor       bool
+	ucspmSystem         string
 }
 
 type ReportData struct {
 	timestamp string
+	unix      int64
 	value     float64
 }
 
+type ReportStats struct {
+	datapoints   int
+	min          float64
+	mean         float64
+	weightedMean float64
+	max          float64
+	p95          float64
+	p99          float64
+	burst        time.Duration
+	coreHours    float64
+}
+
 type dataSlice []ReportData
 
 // Len is part of sort.Interface.
@@ -134,7 +299,7 @@ func (d dataSlice) Swap(i, j int) {
 	d[i], d[j] = d[j], d[i]
 }
 
-// Less is part of sort.Interface. We use count as the value to sort by
+// Less is part of sort.Interface. We use the unix timestamp as the value to sort by
 func (d dataSlice) Less(i, j int) bool {
-	return d[i].timestamp < d[j].timestamp
+	return d[i].unix < d[j].unix
 }
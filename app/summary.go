@@ -0,0 +1,28 @@
+package app
+
+import (
+	"github.com/robjporter/go-functions/as"
+)
+
+func (a *Application) summaryExportToCSV() {
+	a.LogInfo("Saving utilisation summary for all servers.", map[string]interface{}{"Servers": len(a.Results)}, false)
+	csv := "domain,serial,model,position,serviceprofile,org,costcentre,hypervisor,name,uid,ucspm,datapoints,min,mean,weightedmean,max,p95,p99,burstseconds,corehours"
+	for _, metric := range a.Report.Query.Extra {
+		csv += "," + metric.Name + "mean," + metric.Name + "max"
+	}
+	csv += "\n"
+	for i := 0; i < len(a.Results); i++ {
+		stats := a.Results[i].stats
+		csv += a.Results[i].ucsSystem + "," + a.Results[i].ucsSerial + "," + a.Results[i].ucsModel + "," + a.Results[i].ucsPosition + ","
+		csv += a.Results[i].ucsServiceProfile + "," + a.Results[i].ucsOrg + "," + a.Results[i].costCentre + ","
+		csv += a.Results[i].ucspmHypervisorName + "," + a.Results[i].ucspmName + "," + a.Results[i].ucspmUID + "," + a.Results[i].ucspmSystem + "," + as.ToString(stats.datapoints) + ","
+		csv += formatFloat(stats.min) + "," + formatFloat(stats.mean) + "," + formatFloat(stats.weightedMean) + "," + formatFloat(stats.max) + ","
+		csv += formatFloat(stats.p95) + "," + formatFloat(stats.p99) + "," + as.ToString(int64(stats.burst.Seconds())) + "," + formatFloat(stats.coreHours)
+		for _, metric := range a.Report.Query.Extra {
+			series := a.Results[i].metrics[metric.Name].series()
+			csv += "," + formatFloat(series.Mean()) + "," + formatFloat(series.Max())
+		}
+		csv += "\n"
+	}
+	a.saveFile("Stage6-Summary-"+a.Report.Label+".csv", csv)
+}
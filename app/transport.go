@@ -0,0 +1,97 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"../transport"
+)
+
+const (
+	defaultHTTPRetries          = 3
+	defaultHTTPBackoff          = time.Second
+	defaultHTTPMaxBackoff       = 30 * time.Second
+	defaultHTTPBreakerThreshold = 5
+	defaultHTTPBreakerCooldown  = time.Minute
+)
+
+// transportOptions reads the TLS, proxy and source address settings for a
+// system. The tls, proxy and network sections apply to every system that
+// does not set its own value.
+func (a *Application) transportOptions(options map[string]string) transport.Options {
+	var opts transport.Options
+	opts.CAFile = a.systemOption(options, "cafile", "tls.cafile")
+	opts.Pins = transport.ParsePins(options["pins"])
+	opts.Insecure, _ = strconv.ParseBool(strings.TrimSpace(options["insecure"]))
+	opts.Proxy = a.systemOption(options, "proxy", "proxy.url")
+	opts.ProxyUsername = a.systemOption(options, "proxyusername", "proxy.username")
+	if password := a.systemOption(options, "proxypassword", "proxy.password"); password != "" {
+		opts.ProxyPassword = a.DecryptPassword(password)
+	}
+	opts.NoProxy = transport.ParseList(a.systemOption(options, "noproxy", "proxy.noproxy"))
+	opts.Source = a.systemOption(options, "source", "network.source")
+	opts.ConnectTimeout = a.Config.GetDuration("http.connecttimeout")
+	opts.ReadTimeout = a.Config.GetDuration("http.readtimeout")
+	return opts
+}
+
+func (a *Application) httpRetryPolicy() transport.RetryPolicy {
+	policy := transport.RetryPolicy{Attempts: defaultHTTPRetries + 1, BaseDelay: defaultHTTPBackoff, MaxDelay: defaultHTTPMaxBackoff}
+	if a.Config.IsSet("http.retries") {
+		policy.Attempts = a.Config.GetInt("http.retries") + 1
+	}
+	if a.Config.GetDuration("http.backoff") > 0 {
+		policy.BaseDelay = a.Config.GetDuration("http.backoff")
+	}
+	if a.Config.GetDuration("http.maxbackoff") > 0 {
+		policy.MaxDelay = a.Config.GetDuration("http.maxbackoff")
+	}
+	return policy
+}
+
+// httpBreaker returns the circuit breaker shared by every client, so that an
+// endpoint failing for one worker is skipped by all of them.
+func (a *Application) httpBreaker() *transport.Breaker {
+	a.breakerOnce.Do(func() {
+		threshold := defaultHTTPBreakerThreshold
+		if a.Config.IsSet("http.breakerthreshold") {
+			threshold = a.Config.GetInt("http.breakerthreshold")
+		}
+		cooldown := defaultHTTPBreakerCooldown
+		if a.Config.GetDuration("http.breakercooldown") > 0 {
+			cooldown = a.Config.GetDuration("http.breakercooldown")
+		}
+		a.breaker = transport.NewBreaker(threshold, cooldown)
+	})
+	return a.breaker
+}
+
+func (a *Application) systemOption(options map[string]string, key string, global string) string {
+	if value := strings.TrimSpace(options[key]); value != "" {
+		return value
+	}
+	return strings.TrimSpace(a.Config.GetString(global))
+}
+
+func (a *Application) newHTTPClient(url string, options map[string]string) (*http.Client, error) {
+	opts := a.transportOptions(options)
+	if opts.Insecure {
+		a.LogWarn("Certificate verification is disabled for this system.", map[string]interface{}{"URL": url}, false)
+	}
+	if opts.Proxy != "" || opts.Source != "" {
+		a.Log("Using custom network settings for this system.", map[string]interface{}{"URL": url, "Proxy": redactProxy(opts.Proxy), "NoProxy": opts.NoProxy, "Source": opts.Source}, true)
+	}
+	return transport.NewClient(opts)
+}
+
+func redactProxy(proxy string) string {
+	if i := strings.LastIndex(proxy, "@"); i > -1 {
+		if j := strings.Index(proxy, "://"); j > -1 && j < i {
+			return proxy[:j+3] + "***" + proxy[i:]
+		}
+		return "***" + proxy[i:]
+	}
+	return proxy
+}
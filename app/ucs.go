@@ -1,19 +1,29 @@
 package app
 
 import (
-	"fmt"
+	"errors"
 	"strings"
 
-	"github.com/robjporter/go-functions/etree"
-	"github.com/robjporter/go-functions/http"
+	"../matching"
+	"../ucsm"
+	"github.com/robjporter/go-functions/as"
+)
+
+const (
+	ucsInventoryModeBulk = "bulk"
+	ucsInventoryModeDn   = "dn"
 )
 
 func (a *Application) ucsExportToCSV() {
-	csv := "name,uuid,serial,domain,domainversion,position,model,pid,description,dn\n"
+	csv := "name,uuid,matcheduuid,matchstrategy,matchconfidence,serial,domain,domainversion,position,model,pid,description,dn,serviceprofile,org,cpumodel,cpuspeed,sockets,cores,enabledcores,threads\n"
 	for i := 0; i < len(a.UCS.Matched); i++ {
-		csv += a.UCS.Matched[i].servername + "," + a.UCS.Matched[i].serveruuid + "," + a.UCS.Matched[i].serverserial + ","
+		csv += a.UCS.Matched[i].servername + "," + a.UCS.Matched[i].serveruuid + "," + a.UCS.Matched[i].matcheduuid + "," + a.UCS.Matched[i].matchstrategy + "," + a.UCS.Matched[i].matchconfidence + ","
+		csv += a.UCS.Matched[i].serverserial + ","
 		csv += a.UCS.Matched[i].ucsname + "," + a.UCS.Matched[i].ucsversion + "," + a.UCS.Matched[i].serverposition + "," + a.UCS.Matched[i].servermodel + ","
-		csv += a.UCS.Matched[i].serverpid + "," + a.UCS.Matched[i].serverdescr + "," + a.UCS.Matched[i].serverdn + "\n"
+		csv += a.UCS.Matched[i].serverpid + "," + a.UCS.Matched[i].serverdescr + "," + a.UCS.Matched[i].serverdn + ","
+		csv += a.UCS.Matched[i].serviceprofile + "," + a.UCS.Matched[i].serviceorg + ","
+		csv += a.UCS.Matched[i].cpumodel + "," + a.UCS.Matched[i].cpuspeed + "," + as.ToString(a.UCS.Matched[i].cpusockets) + ","
+		csv += as.ToString(a.UCS.Matched[i].cpucores) + "," + as.ToString(a.UCS.Matched[i].cpuenabled) + "," + as.ToString(a.UCS.Matched[i].cputhreads) + "\n"
 
 	}
 	if a.Config.IsSet("output.file") {
@@ -25,55 +35,32 @@ func (a *Application) ucsProcessMatchedUUID() {
 	a.LogInfo("Starting UUID Match process.", map[string]interface{}{"UUID": len(a.UCS.UUID), "Servers": len(a.UCS.Matches), "Domains": len(a.UCS.Systems)}, true)
 	unmatched := make([]string, len(a.UCS.UUID))
 	copy(unmatched, a.UCS.UUID)
+	a.ucsBuildMatchIndexes()
+	skip := a.ucsApplyOverrides(unmatched)
+	a.ucsDetectConflicts(unmatched, skip)
+	overrides := a.matchOverrides()
 	for i := 0; i < len(a.UCS.Matches); i++ {
-		matched := false
-		for j := 0; j < len(a.UCS.UUID); j++ {
-			if !matched {
-				if a.UCS.Matches[i].serveruuid == a.UCS.UUID[j] {
-					a.UCS.Matched = append(a.UCS.Matched, a.UCS.Matches[i])
-					unmatched[j] = "REMOVE"
-					matched = true
-				} else if a.UCS.Matches[i].serverouuid == a.UCS.UUID[j] {
-					a.UCS.Matches[i].serveruuid = a.UCS.Matches[i].serverouuid
-					a.UCS.Matched = append(a.UCS.Matched, a.UCS.Matches[i])
-					unmatched[j] = "REMOVE"
-					matched = true
-				} else if a.transposeUUID(a.UCS.Matches[i].serveruuid) == a.UCS.UUID[j] {
-					a.UCS.Matches[i].serveruuid = a.transposeUUID(a.UCS.Matches[i].serveruuid)
-					a.UCS.Matched = append(a.UCS.Matched, a.UCS.Matches[i])
-					unmatched[j] = "REMOVE"
-					matched = true
-				}
+		if a.UCS.Matches[i].matchstrategy != "" {
+			continue
+		}
+		candidates := matching.Candidates(a.UCS.uuidIndex, ucsMatchServer(a.UCS.Matches[i]), a.matchStrategyEnabled)
+		for j := 0; j < len(candidates); j++ {
+			uuid := a.UCS.UUID[candidates[j].Position]
+			if skip[matching.Normalise(uuid)] || !ucsOverrideAllows(overrides, a.UCS.Matches[i], uuid) {
+				continue
 			}
+			a.UCS.Matches[i].matcheduuid = uuid
+			a.UCS.Matches[i].matchstrategy = candidates[j].Strategy
+			a.UCS.Matches[i].matchconfidence = matchConfidence(candidates[j].Strategy)
+			a.UCS.Matched = append(a.UCS.Matched, a.UCS.Matches[i])
+			unmatched[candidates[j].Position] = "REMOVE"
+			break
 		}
 	}
 	a.ucsRemoveMatched(unmatched)
-}
-
-func (a *Application) transposeUUID(uuid string) string {
-	splits := strings.Split(uuid, "-")
-	if len(splits) == 5 {
-		splits[0] = rotateNumber(splits[0])
-		splits[1] = rotateNumber(splits[1])
-		splits[2] = rotateNumber(splits[2])
-		uuid = splits[0] + "-" + splits[1] + "-" + splits[2] + "-" + splits[3] + "-" + splits[4]
-	}
-	return uuid
-}
-
-func rotateNumber(number string) string {
-	if len(number) == 4 {
-		part1 := number[0:2]
-		part2 := number[2:4]
-		number = part2 + part1
-	} else if len(number) == 8 {
-		part1 := number[0:2]
-		part2 := number[2:4]
-		part3 := number[4:6]
-		part4 := number[6:8]
-		number = part4 + part3 + part2 + part1
-	}
-	return number
+	a.ucsProcessFallbackMatches()
+	a.ucsIndexMatched()
+	a.ucsBuildMatchAudit()
 }
 
 func (a *Application) ucsRemoveMatched(list []string) {
@@ -105,7 +92,8 @@ func (a *Application) ucsReadUCSPMUUIDFile() {
 }
 
 func (a *Application) ucsInit() {
-
+	a.ucsLoadMatchStrategies()
+	a.ucsLoadOverrides()
 }
 
 func (a *Application) ucsInventory() {
@@ -115,107 +103,112 @@ func (a *Application) ucsInventory() {
 	}
 	a.ucsConnection()
 	a.ucsGetAllUUIDInfo()
-	a.ucsLogoutDomains()
 	a.ucsReadUCSPMUUIDFile()
 	a.ucsProcessMatchedUUID()
+	a.ucsGetProcessorInfo()
+	a.ucsLogoutDomains()
 	a.ucsExportToCSV()
 }
 
 func (a *Application) ucsConnection() {
-	for i := 0; i < len(a.UCS.Systems); i++ {
-		cookie, version := a.ucsConnectToSystem(a.UCS.Systems[i])
-		if cookie != "" {
-			a.UCS.Systems[i].cookie = cookie
-			a.UCS.Systems[i].version = version
+	runWorkers(len(a.UCS.Systems), a.getWorkerCount("ucs.workers", defaultUCSWorkers), func(i int) {
+		client, err := a.ucsNewClient(a.UCS.Systems[i])
+		if err != nil {
+			a.Log("Failed to set up the connection to UCS System.", map[string]interface{}{"URL": a.UCS.Systems[i].ip, "Error": err}, false)
+			return
+		}
+		a.UCS.Systems[i].client = client
+		err = a.ucsConnectToSystem(a.UCS.Systems[i])
+		if err == nil {
+			a.UCS.Systems[i].session = a.ucsNewSession(a.UCS.Systems[i])
+			a.UCS.Systems[i].session.Start()
+			a.UCS.Systems[i].version = a.UCS.Systems[i].client.Version()
 			a.UCS.Systems[i].name = a.ucsGetSystemName(a.UCS.Systems[i])
 			a.LogInfo("Successfully connected to UCS System.", map[string]interface{}{"URL": a.UCS.Systems[i].ip, "Name": a.UCS.Systems[i].name, "Version": a.UCS.Systems[i].version}, false)
 		} else {
-			a.Log("Failed to connect to UCS System.", map[string]interface{}{"URL": a.UCS.Systems[i].ip}, true)
+			a.Log("Failed to connect to UCS System.", map[string]interface{}{"URL": a.UCS.Systems[i].ip, "Error": err}, true)
 		}
-	}
+	})
 }
 
-func (a *Application) ucsConnectToSystem(sys UCSSystemInfo) (string, string) {
-	xml, err := ucsGetLoginXML()
-	headers := make(map[string]string)
-	result := ""
-	version := ""
-	if err == nil {
-		headers["Content-Type"] = "application/xml"
-		xml = replaceString(xml, "|USERNAME|", sys.username)
-		xml = replaceString(xml, "|PASSWORD|", a.DecryptPassword(sys.password))
-		code, response, err := http.SendUnsecureHTTPSRequest(sys.ip, "POST", xml, headers)
-
-		a.addCommand(sys.ip, xml, headers, response, code, err)
+func (a *Application) ucsNewClient(sys UCSSystemInfo) (*ucsm.Client, error) {
+	httpClient, err := a.newHTTPClient(sys.ip, sys.options)
+	if err != nil {
+		return nil, err
+	}
+	client := ucsm.New(sys.ip, sys.username, a.DecryptPassword(sys.password))
+	client.HTTPClient = httpClient
+	client.Record = a.addCommand
+	client.Retry = a.httpRetryPolicy()
+	client.Breaker = a.httpBreaker()
+	return client, nil
+}
 
+func (a *Application) ucsNewSession(sys UCSSystemInfo) *ucsm.SessionManager {
+	session := ucsm.NewSessionManager(sys.client)
+	session.OnRefresh = func(err error) {
 		if err == nil {
-			if code == 200 {
-				result = getQueryResponseData(response, "aaaLogin", "", "outCookie")
-				version = getQueryResponseData(response, "aaaLogin", "", "outVersion")
-			} else {
-				a.Log("Failed to gain a 200 response code.", map[string]interface{}{"URL": sys.ip, "Code": code, "Response": response}, true)
-			}
+			a.Log("Refreshed UCS Domain session.", map[string]interface{}{"URL": sys.ip, "RefreshPeriod": sys.client.RefreshPeriod()}, true)
 		} else {
-			a.Log("Failed to connect to UCS System.", map[string]interface{}{"URL": sys.ip, "ERROR": err}, true)
+			a.LogWarn("Failed to refresh UCS Domain session.", map[string]interface{}{"URL": sys.ip, "Error": err}, false)
 		}
 	}
-	return result, version
-}
-
-func (a *Application) ucsGetSystemName(sys UCSSystemInfo) string {
-	xml, err := ucsGetSystemDetailXML()
-	headers := make(map[string]string)
-	result := ""
-	if err == nil {
-		headers["Content-Type"] = "application/xml"
-		xml = replaceString(xml, "|COOKIE|", sys.cookie)
-		code, response, err := http.SendUnsecureHTTPSRequest(sys.ip, "POST", xml, headers)
-
-		a.addCommand(sys.ip, xml, headers, response, code, err)
-
+	session.OnRelogin = func(err error) {
 		if err == nil {
-			if code == 200 {
-				result = getQueryResponseData2(response, "configResolveClass", "outConfigs", "topSystem", "name")
-			} else {
-				a.Log("Failed to gain a 200 response code.", map[string]interface{}{"URL": sys.ip, "Code": code, "Response": response}, true)
-			}
+			a.LogInfo("Logged back in to UCS Domain.", map[string]interface{}{"URL": sys.ip}, false)
 		} else {
-			a.Log("Failed to connect to UCS System.", map[string]interface{}{"URL": sys.ip, "ERROR": err}, true)
+			a.LogWarn("Failed to log back in to UCS Domain.", map[string]interface{}{"URL": sys.ip, "Error": err}, false)
 		}
 	}
-	return result
+	return session
 }
 
-func (a *Application) ucsLogoutDomain(sys UCSSystemInfo) bool {
-	xml, err := getLogoutXML()
-	headers := make(map[string]string)
-	result := false
-	if err == nil {
-		headers["Content-Type"] = "application/xml"
-		xml = replaceString(xml, "|COOKIE|", sys.cookie)
-		code, response, err := http.SendUnsecureHTTPSRequest(sys.ip, "POST", xml, headers)
+func (a *Application) ucsIsConnected(sys UCSSystemInfo) bool {
+	return sys.client != nil && sys.client.Cookie() != ""
+}
 
-		a.addCommand(sys.ip, xml, headers, response, code, err)
+func (a *Application) ucsConnectToSystem(sys UCSSystemInfo) error {
+	_, err := sys.client.Login()
+	return err
+}
 
-		if err == nil {
-			if code == 200 {
-				result = true
-				a.Log("Succesfully logged out of UCS Domain.", map[string]interface{}{"URL": sys.ip, "Code": code, "Response": response}, true)
-			} else {
-				a.Log("Failed to gain a 200 response code.", map[string]interface{}{"URL": sys.ip, "Code": code, "Response": response}, true)
-			}
-		} else {
-			a.Log("Failed to connect to UCS System.", map[string]interface{}{"URL": sys.ip, "ERROR": err}, true)
-		}
+func (a *Application) ucsGetSystemName(sys UCSSystemInfo) string {
+	var configs *ucsm.Configs
+	err := sys.session.Do(func() error {
+		var err error
+		configs, err = sys.client.ResolveClass("topSystem", false)
+		return err
+	})
+	if err != nil {
+		a.Log("Failed to get the UCS System name.", map[string]interface{}{"URL": sys.ip, "Error": err}, true)
+		return ""
 	}
-	return result
+	if len(configs.TopSystems) == 0 {
+		return ""
+	}
+	return configs.TopSystems[0].Name
+}
+
+func (a *Application) ucsLogoutDomain(sys UCSSystemInfo) bool {
+	if sys.session != nil {
+		sys.session.Stop()
+	}
+	if !a.ucsIsConnected(sys) {
+		return false
+	}
+	if err := sys.client.Logout(); err != nil {
+		a.Log("Failed to log out of UCS Domain.", map[string]interface{}{"URL": sys.ip, "Error": err}, true)
+		return false
+	}
+	a.Log("Succesfully logged out of UCS Domain.", map[string]interface{}{"URL": sys.ip}, true)
+	return true
 }
 
 func (a *Application) ucsLogoutDomains() {
 	a.LogInfo("Logging out of all UCS Domains.", nil, true)
-	for i := 0; i < len(a.UCS.Systems); i++ {
+	runWorkers(len(a.UCS.Systems), a.getWorkerCount("ucs.workers", defaultUCSWorkers), func(i int) {
 		a.ucsLogoutDomain(a.UCS.Systems[i])
-	}
+	})
 }
 
 func (a *Application) ucsMakeConnectionURL(position int) {
@@ -229,260 +222,298 @@ func (a *Application) ucsMakeConnectionURL(position int) {
 	a.Log("Changing UCS System connection URL.", map[string]interface{}{"Original": tmpURL, "Corrected": a.UCS.Systems[position].ip}, true)
 }
 
+// ucsGetAllUUIDInfo queries the domains in parallel, but each domain's servers
+// are added to UCS.Matches in the same order as the domains in the config file.
 func (a *Application) ucsGetAllUUIDInfo() {
-	a.LogInfo("Getting all UCS System UUID Inventory.", nil, true)
-	for i := 0; i < len(a.UCS.Systems); i++ {
-		a.ucsGetUCSData(a.UCS.Systems[i])
-	}
-}
-
-func (a *Application) ucsGetUCSData(sys UCSSystemInfo) {
-	result := a.ucsGetServerInfo(sys)
-	getBladeDNs := ucsGetServerDN(result)
-	a.LogInfo("Getting UCS System Server Detail.", map[string]interface{}{"URL": sys.ip, "Servers": len(getBladeDNs)}, true)
-	for i := 0; i < len(getBladeDNs); i++ {
-		result := a.ucsGetServerDetail(getBladeDNs[i], sys)
-		var mat UCSSystemMatchInfo
-		model, name, ouuid, pid, serial, uuid, position, description := getServerDetail(result)
-		mat.serverdn = getBladeDNs[i]
-		mat.servermodel = model
-		mat.serverdescr = description
-		mat.servername = name
-		mat.serverpid = pid
-		mat.serverposition = ucsFormatPosition(position)
-		mat.serverserial = serial
-		mat.serveruuid = uuid
-		mat.serverouuid = ouuid
-		mat.ucsname = sys.name
-		mat.ucsversion = sys.version
-		a.UCS.Matches = append(a.UCS.Matches, mat)
+	workers := a.getWorkerCount("ucs.workers", defaultUCSWorkers)
+	a.LogInfo("Getting all UCS System UUID Inventory.", map[string]interface{}{"Domains": len(a.UCS.Systems), "Workers": workers}, true)
+	results := make([][]UCSSystemMatchInfo, len(a.UCS.Systems))
+	runWorkers(len(a.UCS.Systems), workers, func(i int) {
+		if a.ucsIsConnected(a.UCS.Systems[i]) {
+			results[i] = a.ucsGetUCSData(a.UCS.Systems[i])
+		}
+	})
+	for i := 0; i < len(results); i++ {
+		a.UCS.Matches = append(a.UCS.Matches, results[i]...)
 	}
 }
 
-func (a *Application) ucsGetUCSSystem(uuid string) UCSSystemMatchInfo {
-	for i := 0; i < len(a.UCS.Matched); i++ {
-		if a.UCS.Matched[i].serveruuid == uuid {
-			return a.UCS.Matched[i]
+// ucsGetUCSData reads every blade and rack unit with a single
+// configResolveClasses call. Older firmware that rejects it, or setting
+// ucs.inventorymode to "dn", falls back to one configResolveDn per server.
+func (a *Application) ucsGetUCSData(sys UCSSystemInfo) []UCSSystemMatchInfo {
+	var matches []UCSSystemMatchInfo
+	var err error
+	if strings.ToLower(a.Config.GetString("ucs.inventorymode")) != ucsInventoryModeDn {
+		matches, err = a.ucsGetUCSDataBulk(sys)
+		if err != nil {
+			a.LogWarn("Bulk UCS inventory failed, falling back to querying each server.", map[string]interface{}{"URL": sys.ip, "Error": err}, false)
 		}
 	}
-	return UCSSystemMatchInfo{}
+	if matches == nil {
+		matches = a.ucsGetUCSDataByDn(sys)
+	}
+	a.ucsResolveServiceProfiles(sys, matches)
+	return matches
 }
 
-/*
-	UCS HELPER FUNCTIONS
-*/
-
-func (a *Application) ucsGetServerDetail(dn string, sys UCSSystemInfo) string {
-	xml, err := ucsGetServerDetailXML()
-	headers := make(map[string]string)
-	result := ""
+// ucsResolveServiceProfiles records the service profile and org each server is
+// associated with. All profiles are read in one call, falling back to
+// resolving each assignedToDn.
+func (a *Application) ucsResolveServiceProfiles(sys UCSSystemInfo, matches []UCSSystemMatchInfo) {
+	if len(matches) == 0 {
+		return
+	}
+	profiles := make(map[string]ucsm.LsServer)
+	var configs *ucsm.Configs
+	err := sys.session.Do(func() error {
+		var err error
+		configs, err = sys.client.ResolveClass("lsServer", false)
+		return err
+	})
 	if err == nil {
-		headers["Content-Type"] = "application/xml"
-		xml = replaceString(xml, "|COOKIE|", sys.cookie)
-		xml = replaceString(xml, "|DN|", dn)
-		code, response, err := http.SendUnsecureHTTPSRequest(sys.ip, "POST", xml, headers)
-
-		a.addCommand(sys.ip, xml, headers, response, code, err)
-
-		if err == nil {
-			if code == 200 {
-				result = response
-			}
-		} else {
-			fmt.Println(err)
+		for i := 0; i < len(configs.Profiles); i++ {
+			profiles[configs.Profiles[i].Dn] = configs.Profiles[i]
 		}
+	} else {
+		a.LogWarn("Failed to get all UCS service profiles, resolving each one instead.", map[string]interface{}{"URL": sys.ip, "Error": err}, false)
 	}
-	return result
-}
-
-func (a *Application) ucsGetServerInfo(sys UCSSystemInfo) string {
-	xml, err := ucsGetAllServersXML()
-	result := ""
-	headers := make(map[string]string)
-
-	if err == nil {
-		headers["Content-Type"] = "application/xml"
-		xml = replaceString(xml, "|COOKIE|", sys.cookie)
-
-		code, response, err := http.SendUnsecureHTTPSRequest(sys.ip, "POST", xml, headers)
-
-		a.addCommand(sys.ip, xml, headers, response, code, err)
-
-		if err == nil {
-			if code == 200 {
-				result = response
-			}
+	workers := a.getWorkerCount("ucs.domainworkers", defaultUCSDomainWorkers)
+	runWorkers(len(matches), workers, func(i int) {
+		dn := matches[i].serverassigned
+		if dn == "" {
+			return
+		}
+		profile, ok := profiles[dn]
+		if !ok && err != nil {
+			profile, ok = a.ucsGetServiceProfile(sys, dn)
+		}
+		if ok {
+			matches[i].serviceprofile = profile.Name
+			matches[i].serviceorg = profile.Org()
 		} else {
-			fmt.Println(err)
+			a.Log("Failed to find UCS service profile.", map[string]interface{}{"URL": sys.ip, "DN": matches[i].serverdn, "AssignedTo": dn}, true)
 		}
-	}
-
-	return result
+	})
 }
 
-func ucsGetServerDN(result string) []string {
-	doc := etree.NewDocument()
-	results := []string{}
-	if err := doc.ReadFromString(result); err != nil {
-		panic(err)
-	}
-	root := doc.SelectElement("configFindDnsByClassId")
-	if root != nil {
-		out := root.SelectElement("outDns")
-		if out != nil {
-			for _, server := range out.SelectElements("dn") {
-				dn := server.SelectAttrValue("value", "unknown")
-				results = append(results, dn)
+// ucsGetProcessorInfo records the processors fitted to each matched server in
+// Matches, and copies them into Matched. A domain's processors are read in one
+// call, falling back to a hierarchical query of each matched server.
+func (a *Application) ucsGetProcessorInfo() {
+	runWorkers(len(a.UCS.Systems), a.getWorkerCount("ucs.workers", defaultUCSWorkers), func(i int) {
+		sys := a.UCS.Systems[i]
+		if !a.ucsIsConnected(sys) {
+			return
+		}
+		matched := []int{}
+		for j := 0; j < len(a.UCS.Matches); j++ {
+			if a.UCS.Matches[j].ucsip == sys.ip && a.UCS.Matches[j].matchstrategy != "" {
+				matched = append(matched, j)
 			}
 		}
-	}
-	return results
-}
-
-func getServerDetail(result string) (string, string, string, string, string, string, string, string) {
-	doc := etree.NewDocument()
-	if err := doc.ReadFromString(result); err != nil {
-		panic(err)
-	}
-	root := doc.SelectElement("configResolveDn")
-	if root != nil {
-		out := root.SelectElement("outConfig")
-		if out != nil {
-			var server *etree.Element
-			if server = out.SelectElement("computeRackUnit"); server == nil {
-				if server = out.SelectElement("computeBlade"); server == nil {
-					fmt.Println("ERROR")
-				}
+		if len(matched) == 0 {
+			return
+		}
+		processors, err := a.ucsGetDomainProcessors(sys)
+		if err != nil {
+			a.LogWarn("Failed to get all UCS processors, querying each server instead.", map[string]interface{}{"URL": sys.ip, "Error": err}, false)
+		}
+		for _, j := range matched {
+			units := processors[a.UCS.Matches[j].serverdn]
+			if err != nil {
+				units = a.ucsGetServerProcessors(sys, a.UCS.Matches[j].serverdn)
 			}
+			ucsSetProcessorInfo(&a.UCS.Matches[j], units)
+		}
+		a.LogInfo("Collected UCS processor inventory.", map[string]interface{}{"URL": sys.ip, "Servers": len(matched)}, true)
+	})
 
-			model := server.SelectAttrValue("model", "unknown")
-			name := server.SelectAttrValue("name", "unknown")
-			ouuid := server.SelectAttrValue("originalUuid", "unknown")
-			pid := server.SelectAttrValue("partNumber", "unknown")
-			serial := server.SelectAttrValue("serial", "unknown")
-			uuid := server.SelectAttrValue("uuid", "unknown")
-			position := server.SelectAttrValue("serverId", "unknown")
-			decription := server.SelectAttrValue("descr", "unknown")
-			return model, name, ouuid, pid, serial, uuid, position, decription
+	servers := make(map[string]int)
+	for i := 0; i < len(a.UCS.Matches); i++ {
+		servers[a.UCS.Matches[i].ucsip+"|"+a.UCS.Matches[i].serverdn] = i
+	}
+	for i := 0; i < len(a.UCS.Matched); i++ {
+		if j, ok := servers[a.UCS.Matched[i].ucsip+"|"+a.UCS.Matched[i].serverdn]; ok {
+			a.UCS.Matched[i].cpumodel = a.UCS.Matches[j].cpumodel
+			a.UCS.Matched[i].cpuspeed = a.UCS.Matches[j].cpuspeed
+			a.UCS.Matched[i].cpusockets = a.UCS.Matches[j].cpusockets
+			a.UCS.Matched[i].cpucores = a.UCS.Matches[j].cpucores
+			a.UCS.Matched[i].cpuenabled = a.UCS.Matches[j].cpuenabled
+			a.UCS.Matched[i].cputhreads = a.UCS.Matches[j].cputhreads
 		}
 	}
-	return "", "", "", "", "", "", "", ""
 }
 
-func ucsFormatPosition(pos string) string {
-	result := ""
-	if pos != "" {
-		splits := strings.Split(pos, "/")
-		if len(splits) == 2 {
-			result = "Chassis: " + splits[0] + " | Blade: " + splits[1]
-		} else {
-			result = pos
-		}
+func (a *Application) ucsGetDomainProcessors(sys UCSSystemInfo) (map[string][]ucsm.ProcessorUnit, error) {
+	var configs *ucsm.Configs
+	err := sys.session.Do(func() error {
+		var err error
+		configs, err = sys.client.ResolveClass("processorUnit", false)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	return result
+	processors := make(map[string][]ucsm.ProcessorUnit)
+	for i := 0; i < len(configs.Processors); i++ {
+		dn := configs.Processors[i].ServerDn()
+		processors[dn] = append(processors[dn], configs.Processors[i])
+	}
+	return processors, nil
 }
 
-func getQueryResponseData(xml string, root string, element string, attribute string) string {
-	results := ""
-	doc := etree.NewDocument()
-	if err := doc.ReadFromString(xml); err == nil {
-		root := doc.SelectElement(root)
-		if element == "" {
-			return root.SelectAttrValue(attribute, "unknown")
-		} else {
-			element := root.SelectElement(element)
-			if attribute == "" {
-			} else {
-				return element.SelectAttrValue(attribute, "unknown")
-			}
+func (a *Application) ucsGetServerProcessors(sys UCSSystemInfo, dn string) []ucsm.ProcessorUnit {
+	var configs *ucsm.Configs
+	err := sys.session.Do(func() error {
+		var err error
+		configs, err = sys.client.ResolveDn(dn, true)
+		return err
+	})
+	if err != nil {
+		a.Log("Failed to get UCS Server processors.", map[string]interface{}{"URL": sys.ip, "DN": dn, "Error": err}, true)
+		return nil
+	}
+	units := []ucsm.ProcessorUnit{}
+	servers := configs.Servers()
+	for i := 0; i < len(servers); i++ {
+		for j := 0; j < len(servers[i].Boards); j++ {
+			units = append(units, servers[i].Boards[j].Processors...)
 		}
 	}
-	return results
+	return units
 }
 
-func getQueryResponseData2(xml string, root string, element string, subelement string, attribute string) string {
-	results := ""
-	doc := etree.NewDocument()
-	if err := doc.ReadFromString(xml); err == nil {
-		root2 := doc.SelectElement(root)
-		if root2 != nil {
-			out := root2.SelectElement(element)
-			if out != nil {
-				done := false
-				for _, book := range out.SelectElements(subelement) {
-					if !done {
-						results = book.SelectAttrValue(attribute, "")
-						done = true
-					}
-				}
-			}
+func ucsSetProcessorInfo(mat *UCSSystemMatchInfo, units []ucsm.ProcessorUnit) {
+	for i := 0; i < len(units); i++ {
+		if !units[i].Equipped() {
+			continue
+		}
+		mat.cpusockets++
+		mat.cpucores += int(as.ToInt(units[i].Cores))
+		mat.cpuenabled += int(as.ToInt(units[i].CoresEnabled))
+		mat.cputhreads += int(as.ToInt(units[i].Threads))
+		if mat.cpumodel == "" {
+			mat.cpumodel = strings.TrimSpace(units[i].Model)
+			mat.cpuspeed = units[i].Speed
 		}
 	}
-	return results
 }
 
-func ucsGetSystemDetailXML() (string, error) {
-	doc := etree.NewDocument()
-	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
-	doc.CreateProcInst("xml-stylesheet", `type="text/xsl" href="style.xsl"`)
-
-	resolve := doc.CreateElement("configResolveClass")
-	resolve.CreateAttr("cookie", "|COOKIE|")
-	resolve.CreateAttr("classId", "topSystem")
-	resolve.CreateAttr("inHierarchical", "false")
-
-	doc.Indent(2)
-	return doc.WriteToString()
+func (a *Application) ucsGetServiceProfile(sys UCSSystemInfo, dn string) (ucsm.LsServer, bool) {
+	var configs *ucsm.Configs
+	err := sys.session.Do(func() error {
+		var err error
+		configs, err = sys.client.ResolveDn(dn, false)
+		return err
+	})
+	if err != nil || len(configs.Profiles) == 0 {
+		return ucsm.LsServer{}, false
+	}
+	return configs.Profiles[0], true
 }
 
-func ucsGetServerDetailXML() (string, error) {
-	doc := etree.NewDocument()
-	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
-	doc.CreateProcInst("xml-stylesheet", `type="text/xsl" href="style.xsl"`)
-
-	resolve := doc.CreateElement("configResolveDn")
-	resolve.CreateAttr("dn", "|DN|")
-	resolve.CreateAttr("cookie", "|COOKIE|")
-	resolve.CreateAttr("inHierarchical", "false")
-	doc.Indent(2)
-	return doc.WriteToString()
+func (a *Application) ucsGetUCSDataBulk(sys UCSSystemInfo) ([]UCSSystemMatchInfo, error) {
+	var configs *ucsm.Configs
+	err := sys.session.Do(func() error {
+		var err error
+		configs, err = sys.client.ResolveClasses([]string{"computeBlade", "computeRackUnit"}, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	servers := configs.Servers()
+	a.LogInfo("Getting UCS System Server Detail.", map[string]interface{}{"URL": sys.ip, "Servers": len(servers), "Mode": ucsInventoryModeBulk}, true)
+	matches := make([]UCSSystemMatchInfo, len(servers))
+	for i := 0; i < len(servers); i++ {
+		matches[i] = a.ucsMatchFromServer(sys, servers[i].Dn, servers[i])
+	}
+	return matches, nil
 }
 
-func ucsGetAllServersXML() (string, error) {
-	doc := etree.NewDocument()
-	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
-	doc.CreateProcInst("xml-stylesheet", `type="text/xsl" href="style.xsl"`)
-
-	login := doc.CreateElement("configFindDnsByClassId")
-	login.CreateAttr("classId", "computeItem")
-	login.CreateAttr("cookie", "|COOKIE|")
-	doc.Indent(2)
-	return doc.WriteToString()
+func (a *Application) ucsGetUCSDataByDn(sys UCSSystemInfo) []UCSSystemMatchInfo {
+	var getBladeDNs []string
+	err := sys.session.Do(func() error {
+		var err error
+		getBladeDNs, err = sys.client.FindDnsByClassID("computeItem")
+		return err
+	})
+	if err != nil {
+		a.Log("Failed to get UCS System Servers.", map[string]interface{}{"URL": sys.ip, "Error": err}, true)
+		return nil
+	}
+	workers := a.getWorkerCount("ucs.domainworkers", defaultUCSDomainWorkers)
+	a.LogInfo("Getting UCS System Server Detail.", map[string]interface{}{"URL": sys.ip, "Servers": len(getBladeDNs), "Workers": workers, "Mode": ucsInventoryModeDn}, true)
+	matches := make([]UCSSystemMatchInfo, len(getBladeDNs))
+	runWorkers(len(getBladeDNs), workers, func(i int) {
+		server, err := a.ucsGetServerDetail(getBladeDNs[i], sys)
+		if err != nil {
+			a.Log("Failed to get UCS Server detail.", map[string]interface{}{"URL": sys.ip, "DN": getBladeDNs[i], "Error": err}, true)
+		}
+		matches[i] = a.ucsMatchFromServer(sys, getBladeDNs[i], server)
+	})
+	return matches
 }
 
-func ucsGetLoginXML() (string, error) {
-	doc := etree.NewDocument()
-	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
-	doc.CreateProcInst("xml-stylesheet", `type="text/xsl" href="style.xsl"`)
+func (a *Application) ucsMatchFromServer(sys UCSSystemInfo, dn string, server ucsm.ComputeServer) UCSSystemMatchInfo {
+	var mat UCSSystemMatchInfo
+	mat.serverdn = dn
+	mat.servermodel = server.Model
+	mat.serverdescr = server.Descr
+	mat.servername = server.Name
+	mat.serverpid = server.PartNumber
+	mat.serverposition = ucsFormatPosition(server.ServerID)
+	mat.serverserial = server.Serial
+	mat.serveruuid = server.UUID
+	mat.serverouuid = server.OriginalUUID
+	mat.serverassigned = server.AssignedToDn
+	mat.ucsname = sys.name
+	mat.ucsversion = sys.version
+	mat.ucsip = sys.ip
+	return mat
+}
 
-	login := doc.CreateElement("aaaLogin")
-	login.CreateAttr("inName", "|USERNAME|")
-	login.CreateAttr("inPassword", "|PASSWORD|")
-	doc.Indent(2)
-	return doc.WriteToString()
+func (a *Application) ucsGetUCSSystem(uuid string) UCSSystemMatchInfo {
+	if a.UCS.matchedIndex == nil {
+		a.ucsIndexMatched()
+	}
+	if position := a.UCS.matchedIndex.First(uuid); position > -1 {
+		return a.UCS.Matched[position]
+	}
+	return UCSSystemMatchInfo{}
 }
 
-func getLogoutXML() (string, error) {
-	doc := etree.NewDocument()
-	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
-	doc.CreateProcInst("xml-stylesheet", `type="text/xsl" href="style.xsl"`)
+/*
+	UCS HELPER FUNCTIONS
+*/
 
-	login := doc.CreateElement("aaaLogout")
-	login.CreateAttr("inCookie", "|COOKIE|")
-	doc.Indent(2)
-	return doc.WriteToString()
+func (a *Application) ucsGetServerDetail(dn string, sys UCSSystemInfo) (ucsm.ComputeServer, error) {
+	var configs *ucsm.Configs
+	err := sys.session.Do(func() error {
+		var err error
+		configs, err = sys.client.ResolveDn(dn, false)
+		return err
+	})
+	if err != nil {
+		return ucsm.ComputeServer{}, err
+	}
+	servers := configs.Servers()
+	if len(servers) == 0 {
+		return ucsm.ComputeServer{}, errors.New("No computeBlade or computeRackUnit found")
+	}
+	return servers[0], nil
 }
 
-func replaceString(xml string, param string, value string) string {
-	return strings.Replace(xml, param, value, -1)
+func ucsFormatPosition(pos string) string {
+	result := ""
+	if pos != "" {
+		splits := strings.Split(pos, "/")
+		if len(splits) == 2 {
+			result = "Chassis: " + splits[0] + " | Blade: " + splits[1]
+		} else {
+			result = pos
+		}
+	}
+	return result
 }
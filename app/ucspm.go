@@ -1,109 +1,109 @@
 package app
 
 import (
-	"encoding/base64"
-	"encoding/json"
 	"errors"
+	"math"
 	"math/rand"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
-	"../functions"
-
+	"../matching"
+	"../ucspm"
 	"github.com/robjporter/go-functions/as"
-	"github.com/robjporter/go-functions/http"
-	"github.com/robjporter/go-functions/jmespath"
+)
+
+const (
+	defaultUCSPMPageSize = 100
+	defaultUCSPMSort     = "name"
+	defaultUCSPMSortDir  = "ASC"
 )
 
 func (a *Application) ucspmInit() {
-	a.UCSPM.Routers = make(map[string]string)
-	a.UCSPM.Routers["messaging"] = "MessagingRouter"
-	a.UCSPM.Routers["evconsole"] = "EventsRouter"
-	a.UCSPM.Routers["process"] = "ProcessRouter"
-	a.UCSPM.Routers["service"] = "ServiceRouter"
-	a.UCSPM.Routers["device"] = "DeviceRouter"
-	a.UCSPM.Routers["network"] = "NetworkRouter"
-	a.UCSPM.Routers["template"] = "TemplateRouter"
-	a.UCSPM.Routers["detailnav"] = "DetailNavRouter"
-	a.UCSPM.Routers["report"] = "ReportRouter"
-	a.UCSPM.Routers["mib"] = "MibRouter"
-	a.UCSPM.Routers["zenpack"] = "ZenPackRouter"
-
-	a.UCSPM.TidCount = 1
+	a.UCSPM.Clients = make(map[string]*ucspm.Client)
+	for i := 0; i < len(a.UCSPM.Systems); i++ {
+		sys := a.UCSPM.Systems[i]
+		httpClient, err := a.newHTTPClient(sys.ip, sys.options)
+		if err != nil {
+			a.Log("Failed to set up the connection to UCS Performance Manager.", map[string]interface{}{"URL": sys.ip, "Error": err}, false)
+			continue
+		}
+		client := ucspm.New(sys.ip, sys.username, a.DecryptPassword(sys.password))
+		client.HTTPClient = httpClient
+		client.Record = a.addCommand
+		client.Retry = a.httpRetryPolicy()
+		client.Breaker = a.httpBreaker()
+		a.UCSPM.Clients[sys.ip] = client
+	}
 }
 
-func basicAuth(username, password string) string {
-	auth := username + ":" + password
-	return base64.StdEncoding.EncodeToString([]byte(auth))
+// ucspmClient returns the client set up for a UCS Performance Manager, or an
+// error if its connection could not be set up.
+func (a *Application) ucspmClient(ip string) (*ucspm.Client, error) {
+	if client, ok := a.UCSPM.Clients[ip]; ok {
+		return client, nil
+	}
+	return nil, errors.New("No connection to UCS Performance Manager " + ip)
 }
 
-func (a *Application) getDevices(router string, method string, data string) ([]UCSPMDeviceInfo, error) {
-	a.LogInfo("Getting all UCS Performance Manager Devices", map[string]interface{}{"Router": router, "Method": method, "Data": data}, false)
+func (a *Application) getDevices(sys UCSPMSystemInfo, uid string) ([]UCSPMDeviceInfo, error) {
+	params := ucspm.GetDevicesParams{UID: uid, Limit: defaultUCSPMPageSize, Sort: defaultUCSPMSort, Dir: defaultUCSPMSortDir}
+	if a.Config.GetInt("ucspm.pagesize") > 0 {
+		params.Limit = a.Config.GetInt("ucspm.pagesize")
+	}
+	if a.Config.GetString("ucspm.sort") != "" {
+		params.Sort = a.Config.GetString("ucspm.sort")
+	}
+	if a.Config.GetString("ucspm.dir") != "" {
+		params.Dir = strings.ToUpper(a.Config.GetString("ucspm.dir"))
+	}
+	a.LogInfo("Getting all UCS Performance Manager Devices", map[string]interface{}{"URL": sys.ip, "UID": uid, "PageSize": params.Limit, "Sort": params.Sort, "Dir": params.Dir}, false)
 	devs := []UCSPMDeviceInfo{}
-	jsonStr := `{"action":"` + a.UCSPM.Routers[router] + `","method":"` + method + `","data":` + data + `,"tid":` + as.ToString(a.UCSPM.TidCount) + `}`
-	url := a.makeUCSPMHostname() + "zport/dmd/" + router + "_router"
-	headers := a.getHeaders()
-	code, response, err := http.SendUnsecureHTTPSRequest(url, "POST", jsonStr, headers)
-	a.UCSPM.TidCount++
-
-	a.addCommand(url, jsonStr, headers, response, code, err)
-
-	if err == nil {
-		if code == 200 {
-			if response != "" {
-
-				a.LogInfo("Successfully received response from UCSPM.", map[string]interface{}{"Code": code}, true)
-				var data2 interface{}
-				json.Unmarshal([]byte(response), &data2)
-
-				tmp, err := jmespath.Search("result.totalCount", data2)
-				if err == nil {
-					for i := 0; i < int(as.ToInt(tmp)); i++ {
-						uid, err2 := jmespath.Search("result.devices["+as.ToString(i)+"].uid", data2)
-						name, err3 := jmespath.Search("result.devices["+as.ToString(i)+"].osModel.name", data2)
-						control, err4 := jmespath.Search("result.devices["+as.ToString(i)+"].pythonClass", data2)
-						if err2 == nil {
-							if err4 == nil {
-								if !strings.Contains(as.ToString(control), "ZenPacks.zenoss.ControlCenter.ControlCenter") {
-									var tmp UCSPMDeviceInfo
-									a.Log("UCS Performance Manager Device found", map[string]interface{}{"Name": name, "UID": uid}, true)
-									tmp.uid = as.ToString(uid)
-									splits := strings.Split(as.ToString(uid), "/")
-									tmp.hypervisorShortName = splits[len(splits)-1]
-									tmp.ignore = false
-									tmp.name = as.ToString(name)
-									if err3 == nil {
-										tmp.ishypervisor = a.isVcenter(as.ToString(name))
-									}
-									devs = append(devs, tmp)
-								}
-							}
-						} else {
-							return nil, err2
-						}
-					}
-				} else {
-					return nil, err
+	collected := 0
+	totalCount := 0
+	client, err := a.ucspmClient(sys.ip)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		result, err := client.GetDevices(params)
+		if err != nil {
+			return nil, err
+		}
+		totalCount = result.TotalCount
+		a.LogInfo("Successfully received page of devices from UCSPM.", map[string]interface{}{"Start": params.Start, "Devices": len(result.Devices), "TotalCount": totalCount}, true)
+		for i := 0; i < len(result.Devices); i++ {
+			device := result.Devices[i]
+			if !strings.Contains(device.PythonClass, "ZenPacks.zenoss.ControlCenter.ControlCenter") {
+				var tmp UCSPMDeviceInfo
+				name := ""
+				if device.OSModel != nil {
+					name = device.OSModel.Name
 				}
+				a.Log("UCS Performance Manager Device found", map[string]interface{}{"Name": name, "UID": device.UID}, true)
+				tmp.uid = device.UID
+				splits := strings.Split(device.UID, "/")
+				tmp.hypervisorShortName = splits[len(splits)-1]
+				tmp.ignore = false
+				tmp.name = name
+				tmp.ucspmSystem = sys.ip
+				tmp.ishypervisor = a.isVcenter(name)
+				devs = append(devs, tmp)
 			}
 		}
-	} else {
-		return nil, err
+		collected += len(result.Devices)
+		params.Start += len(result.Devices)
+		if len(result.Devices) == 0 || collected >= totalCount {
+			break
+		}
+	}
+	if collected != totalCount {
+		a.LogWarn("Number of devices collected from UCSPM does not match the total it reported.", map[string]interface{}{"URL": sys.ip, "Collected": collected, "TotalCount": totalCount}, false)
 	}
-	a.LogInfo("UCSPM responded with devices to index.", map[string]interface{}{"Devices": len(devs)}, false)
+	a.LogInfo("UCSPM responded with devices to index.", map[string]interface{}{"Devices": len(devs), "Collected": collected, "TotalCount": totalCount}, false)
 	return devs, nil
 }
 
-func (a *Application) getHeaders() map[string]string {
-	headers := make(map[string]string)
-	headers["Content-type"] = "application/json"
-	headers["Accept-Charset"] = "utf-8"
-	headers["Authorization"] = "Basic " + basicAuth(a.Config.GetString("ucspm.username"), a.DecryptPassword(a.Config.GetString("ucspm.password")))
-	return headers
-}
-
 func (a *Application) isVcenter(name string) bool {
 	if strings.Contains(strings.ToLower(name), "vmware vcenter server") {
 		a.LogInfo("Found a vCenter to index.", map[string]interface{}{"Name": name}, false)
@@ -112,25 +112,23 @@ func (a *Application) isVcenter(name string) bool {
 	return false
 }
 
-func (a *Application) makeUCSPMHostname() string {
-	tmp := a.Config.GetString("ucspm.url")
-	if strings.Contains(tmp, "http") {
-		return tmp
-	} else {
-		tmp = "https://" + tmp + "/"
-	}
-	return tmp
-}
-
 func (a *Application) ucspmInventory() {
-	a.LogInfo("Preparing to run inventory on UCS Performance Manager.", nil, false)
-	err := errors.New("")
-	a.UCSPM.Devices, err = a.getDevices("device", "getDevices", `[{"uid": "/zport/dmd/Devices"}]`)
-	if err == nil {
-		a.ucspmAddHostsUnderVcenters()
-		a.ucspmMarkDevicesToIgnore()
-		a.ucspmGetUUIDForDevices()
+	a.LogInfo("Preparing to run inventory on UCS Performance Manager.", map[string]interface{}{"Systems": len(a.UCSPM.Systems)}, false)
+	a.UCSPM.Devices = nil
+	for i := 0; i < len(a.UCSPM.Systems); i++ {
+		if _, ok := a.UCSPM.Clients[a.UCSPM.Systems[i].ip]; !ok {
+			continue
+		}
+		devs, err := a.getDevices(a.UCSPM.Systems[i], "/zport/dmd/Devices")
+		if err == nil {
+			a.UCSPM.Devices = append(a.UCSPM.Devices, devs...)
+		} else {
+			a.Log("Failed to get devices from UCS Performance Manager.", map[string]interface{}{"URL": a.UCSPM.Systems[i].ip, "Error": err}, false)
+		}
 	}
+	a.ucspmAddHostsUnderVcenters()
+	a.ucspmMarkDevicesToIgnore()
+	a.ucspmGetUUIDForDevices()
 }
 
 func (a *Application) ucspmRemoveDuplicates(elements []string) []string {
@@ -202,159 +200,99 @@ func (a *Application) ucspmGetUUIDForDevices() {
 }
 
 func (a *Application) ucspmGetStandaloneVsphereDeviceDetail(dev UCSPMDeviceInfo) (UCSPMDeviceInfo, error) {
-	router := "DeviceRouter"
-	method := "getInfo"
-	data := `[{"uid": "` + dev.uid + `/datacenters/Datacenter_ha-datacenter/hosts/HostSystem_ha-host","keys": ["hardwareModel","hardwareUUID","hostname","name","hypervisorVersion","device"]}]`
-	jsonStr := `{"action":"` + router + `","method":"` + method + `","data":` + data + `, "tid": ` + as.ToString(a.UCSPM.TidCount) + `}`
-	url := a.makeUCSPMHostname() + strings.TrimLeft(dev.uid, "/") + "/device_router"
-	headers := a.getHeaders()
-	code, response, err := http.SendUnsecureHTTPSRequest(url, "POST", jsonStr, headers)
-	a.UCSPM.TidCount++
-
-	a.addCommand(url, jsonStr, headers, response, code, err)
-
-	if err == nil {
-		if code == 200 {
-			if response != "" {
-				a.LogInfo("Successfully received response from UCSPM.", map[string]interface{}{"Code": code}, true)
-
-				var data2 interface{}
-				json.Unmarshal([]byte(response), &data2)
-
-				uuid, err := jmespath.Search("result.data.hardwareUUID", data2)
-				model, err2 := jmespath.Search("result.data.hardwareModel", data2)
-				hypname, err3 := jmespath.Search("result.data.name", data2)
-				hypversion, err4 := jmespath.Search("result.data.hypervisorVersion", data2)
-				name, err5 := jmespath.Search("result.data.device.name", data2)
-
-				if err == nil && err2 == nil && err3 == nil && err4 == nil && err5 == nil {
-					dev.ishypervisor = true
-					if as.ToString(uuid) == "" {
-						dev.ignore = true
-					} else {
-						dev.name = as.ToString(name)
-						dev.uuid = as.ToString(uuid)
-						dev.model = as.ToString(model)
-						dev.hypervisorName = as.ToString(hypname)
-						dev.hypervisorVersion = as.ToString(hypversion)
-						dev.ucspmName = a.ucspmGenerateUCSPMName(dev)
-						a.Log("UCS Performance Manager Device found", map[string]interface{}{"Name": name, "UUID": uuid}, true)
-
-					}
-					return dev, nil
-				} else {
-					return UCSPMDeviceInfo{}, errors.New("Unknown hardware device")
-				}
-			}
-		}
+	keys := []string{"hardwareModel", "hardwareUUID", "serialNumber", "hostname", "name", "hypervisorVersion", "device"}
+	client, err := a.ucspmClient(dev.ucspmSystem)
+	if err != nil {
+		a.Log("UCS Performance Manager Connection Error", map[string]interface{}{"Error": err, "UID": dev.uid}, true)
+		return UCSPMDeviceInfo{}, err
+	}
+	info, err := client.GetInfo(ucspmStandaloneHostUID(dev.uid), keys)
+	if err != nil {
+		a.Log("UCS Performance Manager Connection Error", map[string]interface{}{"Error": err, "UID": dev.uid}, true)
+		return UCSPMDeviceInfo{}, err
 	}
-	a.Log("UCS Performance Manager Connection Error", map[string]interface{}{"Error": err, "UID": dev.uid}, true)
-	return UCSPMDeviceInfo{}, errors.New("Unsuccessful connection")
+	a.LogInfo("Successfully received response from UCSPM.", map[string]interface{}{"UID": info.UID}, true)
+	if info.Device == nil {
+		return UCSPMDeviceInfo{}, errors.New("Unknown hardware device")
+	}
+	dev.ishypervisor = true
+	if info.HardwareUUID == "" {
+		dev.ignore = true
+	} else {
+		dev.name = info.Device.Name
+		dev.uuid = info.HardwareUUID
+		dev.model = info.HardwareModel
+		dev.serial = info.SerialNumber
+		dev.hypervisorName = info.Name
+		dev.hypervisorVersion = info.HypervisorVersion
+		dev.ucspmName = a.ucspmGenerateUCSPMName(dev)
+		a.Log("UCS Performance Manager Device found", map[string]interface{}{"Name": dev.name, "UUID": dev.uuid}, true)
+	}
+	return dev, nil
 }
 
 func (a *Application) ucspmGetHypervisorDeviceDetail(dev UCSPMDeviceInfo) (UCSPMDeviceInfo, error) {
-	router := "DeviceRouter"
-	method := "getInfo"
-	data := `[{"uid": "` + dev.uid + `","keys": ["hardwareModel","id","hardwareUUID","uuid","hostname"]}]`
-	jsonStr := `{"action":"` + router + `","method":"` + method + `","data":` + data + `,"tid":` + as.ToString(a.UCSPM.TidCount) + `}`
-	url := a.makeUCSPMHostname() + "zport/dmd/device_router"
-	headers := a.getHeaders()
-	code, response, err := http.SendUnsecureHTTPSRequest(url, "POST", jsonStr, headers)
-	a.UCSPM.TidCount++
-
-	a.addCommand(url, jsonStr, headers, response, code, err)
-
-	if err == nil {
-		if code == 200 {
-			if response != "" {
-				a.LogInfo("Successfully received response from UCSPM.", map[string]interface{}{"Code": code}, true)
-
-				var data2 interface{}
-				json.Unmarshal([]byte(response), &data2)
-
-				uuid, err := jmespath.Search("result.data.hardwareUUID", data2)
-				model, err2 := jmespath.Search("result.data.hardwareModel", data2)
-				name, err3 := jmespath.Search("result.data.hostname", data2)
-				hypname, err4 := jmespath.Search("result.data.hostname", data2)
-
-				if err == nil && err2 == nil && err3 == nil && err4 == nil {
-					dev.name = as.ToString(name)
-					dev.uuid = as.ToString(uuid)
-					dev.model = as.ToString(model)
-					dev.hypervisorName = as.ToString(hypname)
-					dev.ishypervisor = true
-					dev.ucspmName = a.ucspmGenerateUCSPMName(dev)
-					a.Log("UCS Performance Manager Device found", map[string]interface{}{"Name": name, "UUID": uuid}, true)
-					return dev, nil
-				} else {
-					return UCSPMDeviceInfo{}, errors.New("Unknown hardware device")
-				}
-			}
-		}
+	keys := []string{"hardwareModel", "id", "hardwareUUID", "uuid", "serialNumber", "hostname"}
+	client, err := a.ucspmClient(dev.ucspmSystem)
+	if err != nil {
+		a.Log("UCS Performance Manager Connection Error", map[string]interface{}{"Error": err, "UID": dev.uid}, true)
+		return UCSPMDeviceInfo{}, err
+	}
+	info, err := client.GetInfo(dev.uid, keys)
+	if err != nil {
+		a.Log("UCS Performance Manager Connection Error", map[string]interface{}{"Error": err, "UID": dev.uid}, true)
+		return UCSPMDeviceInfo{}, err
 	}
-	a.Log("UCS Performance Manager Connection Error", map[string]interface{}{"Error": err, "UID": dev.uid}, true)
-	return UCSPMDeviceInfo{}, errors.New("Unsuccessful connection")
+	a.LogInfo("Successfully received response from UCSPM.", map[string]interface{}{"UID": info.UID}, true)
+	dev.name = info.Hostname
+	dev.uuid = info.HardwareUUID
+	dev.model = info.HardwareModel
+	dev.serial = info.SerialNumber
+	dev.hypervisorName = info.Hostname
+	dev.ishypervisor = true
+	dev.ucspmName = a.ucspmGenerateUCSPMName(dev)
+	a.Log("UCS Performance Manager Device found", map[string]interface{}{"Name": dev.name, "UUID": dev.uuid}, true)
+	return dev, nil
 }
 
 func (a *Application) ucspmAddHostsUnderVcenters() {
-	router := "DeviceRouter"
-	method := "getComponents"
 	count := 0
 	for i := 0; i < len(a.UCSPM.Devices); i++ {
 		if a.UCSPM.Devices[i].ishypervisor {
-			data := `[{"uid":"` + a.UCSPM.Devices[i].uid + `","keys":["uid","id","title","name","hypervisorVersion","totalMemory","uuid"],"meta_type":"vSphereHostSystem","sort":"name","dir":"ASC"}]`
-			jsonStr := `{"action":"` + router + `","method":"` + method + `","data":` + data + `,"tid":` + as.ToString(a.UCSPM.TidCount) + `}`
-			url := a.makeUCSPMHostname() + strings.TrimLeft(a.UCSPM.Devices[i].uid, "/") + "/device_router"
-			headers := a.getHeaders()
-			a.LogInfo("Preparing to inventory servers under discovered hypervisors.", map[string]interface{}{"Router": router, "Method": method, "Data": data, "URL": url}, false)
-
-			code, response, err := http.SendUnsecureHTTPSRequest(url, "POST", jsonStr, headers)
+			params := ucspm.GetComponentsParams{
+				UID:      a.UCSPM.Devices[i].uid,
+				Keys:     []string{"uid", "id", "title", "name", "hypervisorVersion", "totalMemory", "uuid"},
+				MetaType: "vSphereHostSystem",
+				Sort:     "name",
+				Dir:      "ASC",
+			}
+			a.LogInfo("Preparing to inventory servers under discovered hypervisors.", map[string]interface{}{"UID": params.UID, "MetaType": params.MetaType, "URL": a.UCSPM.Devices[i].ucspmSystem}, false)
 
-			a.addCommand(url, jsonStr, headers, response, code, err)
+			var result *ucspm.GetComponentsResult
+			client, err := a.ucspmClient(a.UCSPM.Devices[i].ucspmSystem)
+			if err == nil {
+				result, err = client.GetComponents(params)
+			}
 
-			a.UCSPM.TidCount++
 			a.UCSPM.Devices[i].ignore = true
 
 			if err == nil {
-				if code == 200 {
-					if response != "" {
-						a.LogInfo("Successfully received response from UCSPM.", map[string]interface{}{"Code": code}, true)
-
-						var data2 interface{}
-						json.Unmarshal([]byte(response), &data2)
-
-						tmp, err := jmespath.Search("result.totalCount", data2)
-						if err == nil {
-							count = int(as.ToInt(tmp))
-							for j := 0; j < int(as.ToInt(tmp)); j++ {
-								version, err2 := jmespath.Search("result.data["+as.ToString(j)+"].hypervisorVersion", data2)
-								uid, err4 := jmespath.Search("result.data["+as.ToString(j)+"].uid", data2)
-								name, err5 := jmespath.Search("result.data["+as.ToString(j)+"].name", data2)
-								a.Log("UCS Performance Manager Device found", map[string]interface{}{"Name": name, "UID": uid}, true)
-								if err2 == nil && err4 == nil && err5 == nil {
-									var dev UCSPMDeviceInfo
-									dev.ignore = false
-									dev.hasHypervisor = true
-
-									dev.hypervisorShortName = a.UCSPM.Devices[i].hypervisorShortName
-
-									if err2 == nil {
-										dev.hypervisorVersion = as.ToString(version)
-									}
-									if err4 == nil {
-										dev.uid = as.ToString(uid)
-									}
-
-									if err5 == nil {
-										dev.name = as.ToString(name)
-									}
-
-									a.UCSPM.Devices = append(a.UCSPM.Devices, dev)
-								}
-							}
-						}
-					}
+				a.LogInfo("Successfully received response from UCSPM.", map[string]interface{}{"TotalCount": result.TotalCount}, true)
+				count = result.TotalCount
+				for j := 0; j < len(result.Data); j++ {
+					a.Log("UCS Performance Manager Device found", map[string]interface{}{"Name": result.Data[j].Name, "UID": result.Data[j].UID}, true)
+					var dev UCSPMDeviceInfo
+					dev.ignore = false
+					dev.hasHypervisor = true
+					dev.hypervisorShortName = a.UCSPM.Devices[i].hypervisorShortName
+					dev.ucspmSystem = a.UCSPM.Devices[i].ucspmSystem
+					dev.hypervisorVersion = result.Data[j].HypervisorVersion
+					dev.uid = result.Data[j].UID
+					dev.name = result.Data[j].Name
+					a.UCSPM.Devices = append(a.UCSPM.Devices, dev)
 				}
+			} else {
+				a.LogWarn("Failed to inventory servers under hypervisor.", map[string]interface{}{"UID": params.UID, "Error": err}, false)
 			}
 		}
 	}
@@ -385,7 +323,9 @@ func (a *Application) ucspmMarkDevicesToIgnore() {
 func (a *Application) ucspmProcessDeviceDuplicates() {
 	a.LogInfo("Removing duplicates recevied from UCS Performance Manager.", nil, false)
 	originalCount := a.ucspmGetNonIgnoredDevices()
+	a.ucspmExcludeOverriddenDevices()
 	a.ucspmProcessDiscoveredDevices()
+	overrides := a.matchOverrides()
 	for i := 0; i < len(a.UCSPM.Devices); i++ {
 		if !a.UCSPM.Devices[i].ignore {
 			var tmp CombinedResults
@@ -393,14 +333,29 @@ func (a *Application) ucspmProcessDeviceDuplicates() {
 			tmp.ucspmUID = a.UCSPM.Devices[i].uid
 			tmp.ucspmUUID = a.UCSPM.Devices[i].uuid
 			tmp.ucspmHypervisorName = a.UCSPM.Devices[i].hypervisorShortName
+			tmp.ucspmSystem = a.UCSPM.Devices[i].ucspmSystem
 			tmp2 := a.ucsGetUCSSystem(tmp.ucspmUUID)
 			tmp.ucsDN = tmp2.serverdn
 			tmp.ucsDesc = tmp2.serverdescr
 			tmp.ucsModel = tmp2.servermodel
+			tmp.ucsPID = tmp2.serverpid
 			tmp.ucsName = tmp2.servername
 			tmp.ucsPosition = tmp2.serverposition
 			tmp.ucsSerial = tmp2.serverserial
 			tmp.ucsSystem = tmp2.ucsname
+			tmp.ucsServiceProfile = tmp2.serviceprofile
+			tmp.ucsOrg = tmp2.serviceorg
+			tmp.ucsCPUModel = tmp2.cpumodel
+			tmp.ucsCPUSpeed = tmp2.cpuspeed
+			tmp.ucsCPUSockets = tmp2.cpusockets
+			tmp.ucsCPUCores = tmp2.cpucores
+			tmp.ucsCPUEnabledCores = tmp2.cpuenabled
+			tmp.ucsCPUThreads = tmp2.cputhreads
+			tmp.ucsMatchStrategy = tmp2.matchstrategy
+			tmp.ucsMatchConfidence = tmp2.matchconfidence
+			if override := a.ucspmOverrideForDevice(overrides, a.UCSPM.Devices[i]); override != nil {
+				tmp.costCentre = override.costcentre
+			}
 			tmp.isManaged = a.UCSPM.Devices[i].hasHypervisor
 			a.Results = append(a.Results, tmp)
 		}
@@ -410,16 +365,44 @@ func (a *Application) ucspmProcessDeviceDuplicates() {
 }
 
 func (a *Application) ucspmProcessDiscoveredDevices() {
-	var matched []string
+	conflicts := make(map[string]bool)
+	for i := 0; i < len(a.UCS.Conflicts); i++ {
+		conflicts[matching.Normalise(a.UCS.Conflicts[i].uuid)] = true
+	}
+	matched := make(map[string]bool)
 	for i := len(a.UCSPM.Devices) - 1; i > -1; i-- {
-		if !inStringSlice(matched, a.UCSPM.Devices[i].uuid) {
-			matched = append(matched, a.UCSPM.Devices[i].uuid)
+		if a.UCSPM.Devices[i].ignore {
+			continue
+		}
+		if conflicts[matching.Normalise(a.UCSPM.Devices[i].uuid)] {
+			a.Log("Leaving out device with a conflicting UUID.", map[string]interface{}{"UID": a.UCSPM.Devices[i].uid, "UUID": a.UCSPM.Devices[i].uuid}, true)
+			a.UCSPM.Devices[i].ignore = true
+			continue
+		}
+		uuid := matching.Normalise(a.UCSPM.Devices[i].uuid)
+		if !matched[uuid] {
+			matched[uuid] = true
 		} else {
+			a.Log("Leaving out duplicate device for the same host.", map[string]interface{}{"UID": a.UCSPM.Devices[i].uid, "UUID": a.UCSPM.Devices[i].uuid}, true)
 			a.UCSPM.Devices[i].ignore = true
 		}
 	}
 }
 
+func (a *Application) ucspmExcludeOverriddenDevices() {
+	count := 0
+	overrides := a.matchOverrides()
+	for i := 0; i < len(a.UCSPM.Devices); i++ {
+		override := a.ucspmOverrideForDevice(overrides, a.UCSPM.Devices[i])
+		if override != nil && override.exclude && !a.UCSPM.Devices[i].ignore {
+			a.Log("Excluding device by override.", map[string]interface{}{"UID": a.UCSPM.Devices[i].uid, "UUID": a.UCSPM.Devices[i].uuid}, true)
+			a.UCSPM.Devices[i].ignore = true
+			count++
+		}
+	}
+	a.LogInfo("Excluded devices by override.", map[string]interface{}{"Excluded": count}, false)
+}
+
 func inStringSlice(slice []string, needle string) bool {
 	for i := 0; i < len(slice); i++ {
 		if strings.TrimSpace(needle) == strings.TrimSpace(slice[i]) {
@@ -443,88 +426,111 @@ func (a *Application) ucspmProcessReports() {
 	a.LogInfo("Preparing to Process all data and request reports.", map[string]interface{}{"Requests": len(a.Results)}, false)
 
 	a.ucspmProcessDeviceDuplicates()
-	for i := 0; i < len(a.Results); i++ {
+	workers := a.getWorkerCount("ucspm.workers", defaultUCSPMWorkers)
+	limiter := newRateLimiter(a.Config.GetFloat64("ucspm.ratelimit"))
+	defer limiter.Stop()
+	a.LogInfo("Requesting reports from UCS Performance Manager.", map[string]interface{}{"Requests": len(a.Results), "Workers": workers, "RateLimit": a.Config.GetFloat64("ucspm.ratelimit")}, false)
+
+	errs := make([]error, len(a.Results))
+	runWorkers(len(a.Results), workers, func(i int) {
+		limiter.Wait()
 		if a.Results[i].isManaged {
 			a.Results[i].ucspmKey = createUCSPMKey(a.Results[i].ucspmUID, a.Results[i].ucspmHypervisorName)
-			a.ucspmGetManagedReport(a.Results[i])
+			a.Results[i].metrics, errs[i] = a.ucspmGetManagedReport(a.Results[i])
 		} else {
-			a.ucspmGetUnmanagedReport(a.Results[i])
+			a.Results[i].ucspmKey = createUCSPMKey(ucspmStandaloneHostUID(a.Results[i].ucspmUID), a.Results[i].ucspmHypervisorName)
+			a.Results[i].metrics, errs[i] = a.ucspmGetUnmanagedReport(a.Results[i])
+		}
+		a.Results[i].reportData = a.Results[i].metrics[reportCPUMetric]
+	})
+
+	a.UCSPM.Failures = nil
+	for i := 0; i < len(errs); i++ {
+		if errs[i] != nil {
+			var tmp UCSPMFailureInfo
+			tmp.uid = a.Results[i].ucspmUID
+			tmp.name = a.Results[i].ucspmName
+			tmp.ucspmSystem = a.Results[i].ucspmSystem
+			tmp.err = errs[i].Error()
+			a.UCSPM.Failures = append(a.UCSPM.Failures, tmp)
+			a.LogWarn("Failed to retrieve report from UCS Performance Manager.", map[string]interface{}{"UID": tmp.uid, "UCSPM": tmp.ucspmSystem, "Error": tmp.err}, false)
 		}
 	}
+	a.LogInfo("Finished requesting reports from UCS Performance Manager.", map[string]interface{}{"Requests": len(a.Results), "Failures": len(a.UCSPM.Failures)}, false)
 }
 
-func (a *Application) ucspmGetManagedReport(sys CombinedResults) {
+func (a *Application) ucspmGetManagedReport(sys CombinedResults) (map[string]dataSlice, error) {
 	a.LogInfo("Preparing to request all UCS Performance Manager reports, for managed devices.", nil, false)
-	start := functions.GetTimestampStartOfMonth(a.Report.Month, int(as.ToInt(a.Report.Year)))
-	end := functions.GetTimestampEndOfMonth(a.Report.Month, int(as.ToInt(a.Report.Year)))
-	jsonStr := `
-			{
-	"start": ` + as.ToString(start) + `,
-	"end": ` + as.ToString(end) + `,
-	"series": true,
-	"downsample": "1h-avg",
-	"tags": {},
-	"returnset": "EXACT",
-	"metrics": [{
-		"metric": "` + sys.ucspmHypervisorName + `/cpuUsage_cpuUsage",
-		"rate": false,
-		"rateOptions": {},
-		"aggregator": "avg",
-		"tags": {
-			"key": ["` + sys.ucspmKey + `"]
-		},
-		"name": "Usage-raw",
-		"emit": false
-	}, {
-		"name": "Usage",
-		"expression": "rpn:Usage-raw,100,/"
-	}]
+	return a.ucspmRequestReport(sys)
 }
-		`
-	url := a.makeUCSPMHostname() + "api/performance/query/"
-	headers := a.getHeaders()
-	a.LogInfo("Requesting report.", map[string]interface{}{"ReportStart": start, "ReportEnd": end, "UID": sys.ucspmUID, "Key": sys.ucspmKey, "URL": url}, false)
-
-	code, response, err := http.SendUnsecureHTTPSRequest(url, "POST", jsonStr, headers)
-
-	a.addCommand(url, jsonStr, headers, response, code, err)
-
-	if err == nil {
-		if code == 200 {
-			if response != "" {
-				a.LogInfo("Successfully received response from UCSPM.", map[string]interface{}{"Code": code}, true)
-				var data2 interface{}
-				json.Unmarshal([]byte(response), &data2)
-				tmp, err := jmespath.Search("results[0].datapoints", data2)
-				if err == nil {
-					tmp2 := as.ToSlice(tmp)
-					a.LogInfo("Received Datapoints to process.", map[string]interface{}{"Datapoints": len(tmp2)}, true)
-					a.processReport(sys, tmp2)
-				}
-			}
+
+func (a *Application) ucspmGetUnmanagedReport(sys CombinedResults) (map[string]dataSlice, error) {
+	a.LogInfo("Preparing to request all UCS Performance Manager reports, for unmanaged devices.", nil, false)
+	return a.ucspmRequestReport(sys)
+}
+
+func (a *Application) ucspmRequestReport(sys CombinedResults) (map[string]dataSlice, error) {
+	start := a.Report.Start
+	end := a.Report.End
+	query := a.ucspmBuildReportQuery(sys)
+	a.LogInfo("Requesting report.", map[string]interface{}{"ReportStart": start, "ReportEnd": end, "Downsample": a.Report.Query.Downsample, "Aggregator": a.Report.Query.Aggregator, "Metrics": a.Report.Query.metricNames(), "UID": sys.ucspmUID, "Key": sys.ucspmKey, "URL": sys.ucspmSystem}, false)
+	client, err := a.ucspmClient(sys.ucspmSystem)
+	if err != nil {
+		return nil, err
+	}
+	result, err := client.QueryPerformance(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Results) == 0 {
+		return nil, errors.New("No metric series returned")
+	}
+	a.LogInfo("Received metric series to process.", map[string]interface{}{"Series": len(result.Results)}, true)
+	return a.processReport(sys, result.Results), nil
+}
+
+// processReport stores each series under the name it was requested as. A
+// series with any other name is logged and left out, rather than guessed at.
+func (a *Application) processReport(sys CombinedResults, results []ucspm.PerformanceSeries) map[string]dataSlice {
+	metrics := make(map[string]dataSlice)
+	for i := 0; i < len(results); i++ {
+		name := a.Report.Query.metricName(results[i].Metric)
+		if name == "" {
+			a.LogWarn("Ignoring unknown series in report.", map[string]interface{}{"UID": sys.ucspmUID, "Series": results[i].Metric, "Datapoints": len(results[i].Datapoints)}, false)
+			continue
 		}
+		a.LogInfo("Received Datapoints to process.", map[string]interface{}{"Metric": name, "Datapoints": len(results[i].Datapoints)}, true)
+		metrics[name] = processDatapoints(results[i].Datapoints)
 	}
+	a.outputProcessedReport(sys, metrics)
+	return metrics
 }
 
-func (a *Application) processReport(sys CombinedResults, data []interface{}) {
-	m := make(map[int]ReportData)
+func processDatapoints(data []ucspm.Datapoint) dataSlice {
+	s := make(dataSlice, 0, len(data))
 	for i := 0; i < len(data); i++ {
-		tmp := as.ToStringMap(data[i])
-		ttmp := as.ToInt(strconv.FormatFloat(as.ToFloat(tmp["timestamp"]), 'f', 0, 64))
 		var temp ReportData
-		temp.timestamp = time.Unix(int64(ttmp), 0).Format("Mon Jan _2 2006 15:04:05 ")
-		temp.value = as.ToFloat(tmp["value"])
-		m[i] = temp
-	}
-	s := make(dataSlice, 0, len(m))
-	for _, d := range m {
-		s = append(s, d)
+		temp.unix = int64(math.Floor(data[i].Timestamp + 0.5))
+		temp.timestamp = time.Unix(temp.unix, 0).Format("Mon Jan _2 2006 15:04:05 ")
+		temp.value = data[i].Value
+		s = append(s, temp)
 	}
 	sort.Sort(s)
-	a.outputProcessedReport(sys, s)
+	return s
 }
 
-func (a *Application) outputProcessedReport(sys CombinedResults, data dataSlice) {
+// reportFileUID turns a device UID, which is a path, into something that can
+// be used in a file name.
+func reportFileUID(uid string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, uid), "_")
+}
+
+func (a *Application) outputProcessedReport(sys CombinedResults, metrics map[string]dataSlice) {
 	name := ""
 	if sys.ucspmName != "" {
 		name = sys.ucspmName
@@ -533,21 +539,72 @@ func (a *Application) outputProcessedReport(sys CombinedResults, data dataSlice)
 	} else {
 
 		rand.Seed(time.Now().UTC().UnixNano())
-		name = "server" + as.ToString(rand.Intn(1000-9999))
+		name = "server" + as.ToString(rand.Intn(9000)+1000)
 	}
 
-	filename := name + "-" + sys.ucsSerial + "-" + a.Report.Month + "-" + as.ToString(a.Report.Year) + "-" + as.ToString(time.Now().Unix()) + ".csv"
-
-	csv := "timestamp,value\n"
-	for _, d := range data {
-		csv += as.ToString(d.timestamp) + "," + as.ToString(d.value) + "\n"
+	// The UID keeps reports saved by parallel workers in the same second apart.
+	filename := name + "-" + sys.ucsSerial + "-" + reportFileUID(sys.ucspmUID) + "-" + a.Report.Label + "-" + as.ToString(time.Now().Unix()) + ".csv"
+
+	names := a.Report.Query.metricNames()
+	timestamps := make(map[int64]string)
+	values := make(map[string]map[int64]float64)
+	for _, metric := range names {
+		values[metric] = make(map[int64]float64)
+		for _, d := range metrics[metric] {
+			timestamps[d.unix] = d.timestamp
+			values[metric][d.unix] = d.value
+		}
+	}
+	order := make([]int64, 0, len(timestamps))
+	for ts := range timestamps {
+		order = append(order, ts)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+
+	csv := "timestamp," + strings.Join(names, ",") + "\n"
+	for _, ts := range order {
+		csv += as.ToString(timestamps[ts])
+		for _, metric := range names {
+			csv += ","
+			if value, ok := values[metric][ts]; ok {
+				csv += as.ToString(value)
+			}
+		}
+		csv += "\n"
 	}
+	csv += a.reportStatsRows(sys, names, metrics)
 
 	a.saveFile(filename, csv)
 }
 
-func (a *Application) ucspmGetUnmanagedReport(sys CombinedResults) {
+// reportStatsRows summarises each column of a server's report in rows after
+// the samples, named in the timestamp column. The burst time and core-hours
+// only apply to CPU utilisation.
+func (a *Application) reportStatsRows(sys CombinedResults, names []string, metrics map[string]dataSlice) string {
+	threshold, cores := a.statsOptions()
+	rows := []string{"datapoints", "min", "mean", "weightedmean", "max", "p95", "p99", "burstseconds", "corehours"}
+	columns := make(map[string][]string)
+	for _, metric := range names {
+		stats := metrics[metric].Stats(threshold, statsCores(cores, sys))
+		columns[metric] = []string{as.ToString(stats.datapoints), formatFloat(stats.min), formatFloat(stats.mean), formatFloat(stats.weightedMean), formatFloat(stats.max), formatFloat(stats.p95), formatFloat(stats.p99), "", ""}
+		if metric == reportCPUMetric {
+			columns[metric][7] = as.ToString(int64(stats.burst.Seconds()))
+			columns[metric][8] = formatFloat(stats.coreHours)
+		}
+	}
+	csv := ""
+	for i, row := range rows {
+		csv += row
+		for _, metric := range names {
+			csv += "," + columns[metric][i]
+		}
+		csv += "\n"
+	}
+	return csv
+}
 
+func ucspmStandaloneHostUID(uid string) string {
+	return strings.TrimRight(uid, "/") + "/datacenters/Datacenter_ha-datacenter/hosts/HostSystem_ha-host"
 }
 
 func createUCSPMKey(uid string, hypervisorName string) string {
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultUCSWorkers       = 4
+	defaultUCSDomainWorkers = 4
+	defaultUCSPMWorkers     = 4
+)
+
+// runWorkers calls work once for every index from 0 to count-1, with at most
+// workers calls running at the same time. It returns once every call is done.
+func runWorkers(count int, workers int, work func(int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < count; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				work(i)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func (a *Application) getWorkerCount(key string, fallback int) int {
+	if a.Config.GetInt(key) > 0 {
+		return a.Config.GetInt(key)
+	}
+	return fallback
+}
+
+// rateLimiter spaces out calls to Wait so that no more than the given number
+// happen each second. The first call goes through at once. A limit of zero or
+// less disables it.
+type rateLimiter struct {
+	ticker *time.Ticker
+	tokens chan struct{}
+	done   chan struct{}
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return &rateLimiter{}
+	}
+	r := &rateLimiter{
+		ticker: time.NewTicker(time.Duration(float64(time.Second) / perSecond)),
+		tokens: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	r.tokens <- struct{}{}
+	go func() {
+		for {
+			select {
+			case <-r.ticker.C:
+				select {
+				case r.tokens <- struct{}{}:
+				default:
+				}
+			case <-r.done:
+				return
+			}
+		}
+	}()
+	return r
+}
+
+func (r *rateLimiter) Wait() {
+	if r.ticker != nil {
+		<-r.tokens
+	}
+}
+
+func (r *rateLimiter) Stop() {
+	if r.ticker != nil {
+		r.ticker.Stop()
+		close(r.done)
+	}
+}
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_RateLimiter(t *testing.T) {
+	Convey("Should let the first call through at once", t, func() {
+		limiter := newRateLimiter(1)
+		defer limiter.Stop()
+		start := time.Now()
+		limiter.Wait()
+		So(time.Since(start) < 100*time.Millisecond, ShouldBeTrue)
+	})
+	Convey("Should space out the calls after the first", t, func() {
+		limiter := newRateLimiter(10)
+		defer limiter.Stop()
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			limiter.Wait()
+		}
+		So(time.Since(start) >= 150*time.Millisecond, ShouldBeTrue)
+	})
+}
@@ -2,16 +2,17 @@ package main
 
 import (
 	"bytes"
-	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"time"
+
+	"../transport"
 )
 
 func main() {
-	if len(os.Args) == 6 {
+	if len(os.Args) == 6 || len(os.Args) == 7 {
 		ip := os.Args[1]
 		username := os.Args[2]
 		password := os.Args[3]
@@ -22,11 +23,13 @@ func main() {
 
 		body := bytes.NewBuffer(json)
 
-		// Create client
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		// Create client, the certificate is only left unverified when asked
+		opts := transport.Options{Insecure: len(os.Args) == 7 && os.Args[6] == "--insecure"}
+		client, err := transport.NewClient(opts)
+		if err != nil {
+			fmt.Println("Failure : ", err)
+			return
 		}
-		client := &http.Client{Transport: tr}
 
 		// Create request
 		req, err := http.NewRequest("POST", "https://"+ip+"/api/performance/query/", body)
@@ -41,6 +44,7 @@ func main() {
 
 		if err != nil {
 			fmt.Println("Failure : ", err)
+			return
 		}
 
 		// Read Response Body
@@ -14,6 +14,7 @@ var (
 	output = kingpin.Command("output", "Configure the output file.")
 	input  = kingpin.Command("input", "Configure the input file.")
 	clean  = kingpin.Command("clean", "Clean up from last run.")
+	proxy  = kingpin.Command("proxy", "Configure the proxy used to reach the systems.")
 
 	debug     = kingpin.Command("debug", "Flip debug status.")
 	showDebug = show.Command("debug", "Show debug status")
@@ -50,17 +51,33 @@ var (
 	updateUCSPMUsername = updateUCSPM.Flag("username", "Name of user.").Required().String()
 	updateUCSPMPassword = updateUCSPM.Flag("password", "Password for user in plain text.").Required().String()
 
+	deleteUCSPMIP = deleteUCSPM.Flag("ip", "IP Address or DNS name for UCS Performance Manager, without http(s).").Required().IP()
+
+	showUCSPMIP = showUCSPM.Flag("ip", "IP Address or DNS name for UCS Performance Manager, without http(s).").Required().IP()
+
 	outputFile = output.Flag("set", "Configure the output filename, where the UUID and serial numbers will be saved.").Required().String()
 	inputFile  = input.Flag("set", "Configure the input filename, where the UUID will be read from.").Required().String()
 
-	runMonth = run.Flag("month", "Month process utilisation for").String()
-	runYear  = run.Flag("year", "Year to process utilisation for").String()
+	proxyIP       = proxy.Flag("ip", "IP Address or DNS name of a UCS Manager or UCS Performance Manager to set the proxy for. Defaults to every system.").String()
+	proxyURL      = proxy.Flag("url", "Proxy URL, or none to connect directly.").Required().String()
+	proxyUsername = proxy.Flag("username", "Name of proxy user.").String()
+	proxyPassword = proxy.Flag("password", "Password for proxy user in plain text.").String()
+
+	runMonth      = run.Flag("month", "Month process utilisation for").String()
+	runYear       = run.Flag("year", "Year to process utilisation for").String()
+	runFrom       = run.Flag("from", "Start of the reporting window, as an ISO date (2017-02-01), ISO week (2017-W05) or relative range (last-7d).").String()
+	runTo         = run.Flag("to", "End of the reporting window, as an ISO date or ISO week. Defaults to the end of the --from period.").String()
+	runCustomer   = run.Flag("customer", "Customer whose report settings should be used from the config file.").String()
+	runDownsample = run.Flag("downsample", "Downsample used in the performance query, e.g. 5m-avg.").String()
+	runAggregator = run.Flag("aggregator", "Aggregator used in the performance query, e.g. avg or max.").String()
+	runMetric     = run.Flag("metric", "Metric used in the performance query, e.g. cpuUsage_cpuUsage.").String()
+	runExpression = run.Flag("expression", "Expression applied to the metric, or none to return the raw metric.").String()
 )
 
 func ProcessCommandLineArguments() string {
 	switch kingpin.Parse() {
 	case "run":
-		return "RUN|" + *runMonth + "|" + *runYear
+		return "RUN|" + *runMonth + "|" + *runYear + "|" + *runFrom + "|" + *runTo + "|" + *runCustomer + "|" + *runDownsample + "|" + *runAggregator + "|" + *runMetric + "|" + *runExpression
 	case "clean":
 		return "CLEAN"
 	case "add ucs":
@@ -78,13 +95,15 @@ func ProcessCommandLineArguments() string {
 	case "update ucspm":
 		return "UPDATEUCSPM|" + as.ToString(*updateUCSPMIP) + "|" + *updateUCSPMUsername + "|" + *updateUCSPMPassword
 	case "delete ucspm":
-		return "DELETEUCSPM"
+		return "DELETEUCSPM|" + as.ToString(*deleteUCSPMIP)
 	case "show ucspm":
-		return "SHOWUCSPM"
+		return "SHOWUCSPM|" + as.ToString(*showUCSPMIP)
 	case "input":
 		return "SETINPUT|" + *inputFile
 	case "output":
 		return "SETOUTPUT|" + *outputFile
+	case "proxy":
+		return "SETPROXY|" + *proxyIP + "|" + *proxyURL + "|" + *proxyUsername + "|" + *proxyPassword
 	case "debug":
 		return "DEBUG"
 	case "show debug":
@@ -149,3 +149,99 @@ func getMonthPos(month string) int {
 	}
 	return 0
 }
+
+func GetTimestampRange(from string, to string) (int64, int64) {
+	from = strings.TrimSpace(from)
+	to = strings.TrimSpace(to)
+	if from == "" {
+		return 0, 0
+	}
+	if start, end, ok := ParseRelativeRange(from); ok {
+		if to != "" {
+			return 0, 0
+		}
+		return start.Unix(), end.Unix()
+	}
+	start, end, ok := parseRangeBoundary(from)
+	if !ok {
+		return 0, 0
+	}
+	if to != "" {
+		_, toEnd, ok := parseRangeBoundary(to)
+		if !ok {
+			return 0, 0
+		}
+		end = toEnd
+	}
+	if end.Before(start) {
+		return 0, 0
+	}
+	return start.Unix(), end.Unix()
+}
+
+func parseRangeBoundary(input string) (time.Time, time.Time, bool) {
+	if t, ok := ParseISOWeek(input); ok {
+		return t, t.AddDate(0, 0, 7).Add(-time.Second), true
+	}
+	if t, ok := ParseDate(input); ok {
+		return t, t.AddDate(0, 0, 1).Add(-time.Second), true
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+func ParseDate(input string) (time.Time, bool) {
+	t, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(input), time.Now().Location())
+	if err != nil {
+		return time.Time{}, false
+	}
+	if !isValidYear(as.ToString(t.Year())) {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func ParseISOWeek(input string) (time.Time, bool) {
+	input = strings.ToUpper(strings.TrimSpace(input))
+	splits := strings.Split(strings.Replace(input, "-W", "W", 1), "W")
+	if len(splits) != 2 || !isValidYear(splits[0]) {
+		return time.Time{}, false
+	}
+	year, _ := strconv.Atoi(splits[0])
+	week, err := strconv.Atoi(splits[1])
+	if err != nil || week < 1 || week > isoWeeksInYear(year) {
+		return time.Time{}, false
+	}
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.Now().Location())
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	return monday.AddDate(0, 0, (week-1)*7), true
+}
+
+func isoWeeksInYear(year int) int {
+	_, week := time.Date(year, time.December, 28, 0, 0, 0, 0, time.UTC).ISOWeek()
+	return week
+}
+
+func ParseRelativeRange(input string) (time.Time, time.Time, bool) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	if !strings.HasPrefix(input, "last-") || len(input) < 7 {
+		return time.Time{}, time.Time{}, false
+	}
+	unit := input[len(input)-1:]
+	count, err := strconv.Atoi(input[5 : len(input)-1])
+	if err != nil || count < 1 {
+		return time.Time{}, time.Time{}, false
+	}
+	end := now.New(time.Now()).BeginningOfDay()
+	start := end
+	switch unit {
+	case "d":
+		start = end.AddDate(0, 0, -count)
+	case "w":
+		start = end.AddDate(0, 0, -count*7)
+	case "m":
+		start = end.AddDate(0, -count, 0)
+	default:
+		return time.Time{}, time.Time{}, false
+	}
+	return start, end.Add(-time.Second), true
+}
@@ -262,3 +262,91 @@ func Test_getMonthPos(t *testing.T) {
 		So(getMonthPos("december"), ShouldEqual, 12)
 	})
 }
+
+func Test_ParseDate(t *testing.T) {
+	Convey("Parse date with invalid string", t, func() {
+		_, ok := ParseDate("test")
+		So(ok, ShouldEqual, false)
+	})
+	Convey("Parse date with invalid year", t, func() {
+		_, ok := ParseDate("4444-01-01")
+		So(ok, ShouldEqual, false)
+	})
+	Convey("Parse date with valid ISO date", t, func() {
+		tm, ok := ParseDate("2017-02-14")
+		So(ok, ShouldEqual, true)
+		So(tm.Unix(), ShouldEqual, time.Date(2017, 2, 14, 0, 0, 0, 0, time.Local).Unix())
+	})
+}
+
+func Test_ParseISOWeek(t *testing.T) {
+	Convey("Parse ISO week with invalid string", t, func() {
+		_, ok := ParseISOWeek("test")
+		So(ok, ShouldEqual, false)
+	})
+	Convey("Parse ISO week with invalid week number", t, func() {
+		_, ok := ParseISOWeek("2017-W00")
+		So(ok, ShouldEqual, false)
+		_, ok = ParseISOWeek("2017-W53")
+		So(ok, ShouldEqual, false)
+	})
+	Convey("Parse ISO week with valid week", t, func() {
+		tm, ok := ParseISOWeek("2017-W01")
+		So(ok, ShouldEqual, true)
+		So(tm.Unix(), ShouldEqual, time.Date(2017, 1, 2, 0, 0, 0, 0, time.Local).Unix())
+		tm, ok = ParseISOWeek("2015W53")
+		So(ok, ShouldEqual, true)
+		So(tm.Unix(), ShouldEqual, time.Date(2015, 12, 28, 0, 0, 0, 0, time.Local).Unix())
+	})
+}
+
+func Test_ParseRelativeRange(t *testing.T) {
+	Convey("Parse relative range with invalid string", t, func() {
+		_, _, ok := ParseRelativeRange("test")
+		So(ok, ShouldEqual, false)
+		_, _, ok = ParseRelativeRange("last-0d")
+		So(ok, ShouldEqual, false)
+		_, _, ok = ParseRelativeRange("last-7y")
+		So(ok, ShouldEqual, false)
+	})
+	Convey("Parse relative range with valid string", t, func() {
+		start, end, ok := ParseRelativeRange("last-7d")
+		So(ok, ShouldEqual, true)
+		today := time.Now()
+		So(start.Format("2006-01-02 15:04:05"), ShouldEqual, time.Date(today.Year(), today.Month(), today.Day()-7, 0, 0, 0, 0, time.Local).Format("2006-01-02 15:04:05"))
+		So(end.Format("2006-01-02 15:04:05"), ShouldEqual, time.Date(today.Year(), today.Month(), today.Day()-1, 23, 59, 59, 0, time.Local).Format("2006-01-02 15:04:05"))
+	})
+}
+
+func Test_GetTimestampRange(t *testing.T) {
+	Convey("Get timestamp range with invalid input", t, func() {
+		start, end := GetTimestampRange("test", "")
+		So(start, ShouldEqual, 0)
+		So(end, ShouldEqual, 0)
+	})
+	Convey("Get timestamp range with end before start", t, func() {
+		start, end := GetTimestampRange("2017-02-14", "2017-02-01")
+		So(start, ShouldEqual, 0)
+		So(end, ShouldEqual, 0)
+	})
+	Convey("Get timestamp range with a single date", t, func() {
+		start, end := GetTimestampRange("2017-02-14", "")
+		So(start, ShouldEqual, time.Date(2017, 2, 14, 0, 0, 0, 0, time.Local).Unix())
+		So(end, ShouldEqual, time.Date(2017, 2, 14, 23, 59, 59, 0, time.Local).Unix())
+	})
+	Convey("Get timestamp range with two dates", t, func() {
+		start, end := GetTimestampRange("2017-02-01", "2017-02-07")
+		So(start, ShouldEqual, time.Date(2017, 2, 1, 0, 0, 0, 0, time.Local).Unix())
+		So(end, ShouldEqual, time.Date(2017, 2, 7, 23, 59, 59, 0, time.Local).Unix())
+	})
+	Convey("Get timestamp range with a relative range and an end", t, func() {
+		start, end := GetTimestampRange("last-7d", "2017-02-07")
+		So(start, ShouldEqual, 0)
+		So(end, ShouldEqual, 0)
+	})
+	Convey("Get timestamp range with ISO weeks", t, func() {
+		start, end := GetTimestampRange("2017-W05", "2017-W06")
+		So(start, ShouldEqual, time.Date(2017, 1, 30, 0, 0, 0, 0, time.Local).Unix())
+		So(end, ShouldEqual, time.Date(2017, 2, 12, 23, 59, 59, 0, time.Local).Unix())
+	})
+}
@@ -0,0 +1,43 @@
+package matching
+
+import (
+	"strings"
+)
+
+const (
+	ConflictServers  = "uuid is used by more than one UCS server"
+	ConflictDevices  = "uuid is used by more than one UCS Performance Manager device"
+	ConflictFallback = "ambiguous fallback: device fits more than one UCS server by serial or host name"
+)
+
+// Conflict decides whether uuid is claimed by more than one of servers, or
+// reported by more than one host among devices. Claimed servers, servers an
+// override keeps out, and ignored or excluded devices take no part. Devices
+// with the same host name are the same host discovered twice. It returns the
+// positions in servers and devices that take part, and the reason for the
+// conflict, or an empty reason when there is none.
+func Conflict(uuid string, servers []Server, devices []Device, overrides []Override, enabled func(string) bool) ([]int, []int, string) {
+	claimants := []int{}
+	for i := 0; i < len(servers); i++ {
+		if !servers[i].Claimed && Strategy(servers[i], uuid, enabled) != "" && Allows(overrides, servers[i], uuid) {
+			claimants = append(claimants, i)
+		}
+	}
+
+	reporters := []int{}
+	hosts := make(map[string]bool)
+	for i := 0; i < len(devices); i++ {
+		if devices[i].Ignore || Excludes(overrides, devices[i]) {
+			continue
+		}
+		reporters = append(reporters, i)
+		hosts[strings.ToLower(devices[i].Host)] = true
+	}
+
+	if len(claimants) > 1 {
+		return claimants, reporters, ConflictServers
+	} else if len(hosts) > 1 {
+		return claimants, reporters, ConflictDevices
+	}
+	return claimants, reporters, ""
+}
@@ -0,0 +1,38 @@
+package matching
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_Conflict(t *testing.T) {
+	servers := []Server{{UUID: "aaaa-1", Domain: "dc1"}, {OriginalUUID: "AAAA-1", Domain: "dc2"}, {UUID: "aaaa-1", Domain: "dc3", Claimed: true}}
+	devices := []Device{{UID: "u1", UUID: "aaaa-1", Host: "esx1"}, {UID: "u2", UUID: "aaaa-1", Host: "ESX1"}}
+
+	Convey("Should find a UUID that more than one unclaimed server matches", t, func() {
+		claimants, _, reason := Conflict("aaaa-1", servers, devices, nil, nil)
+		So(reason, ShouldEqual, ConflictServers)
+		So(claimants, ShouldResemble, []int{0, 1})
+	})
+	Convey("Should settle a server conflict with a domain override", t, func() {
+		overrides := []Override{{UUID: "aaaa-1", Domain: "dc2", Resolved: "aaaa-1"}}
+		claimants, _, reason := Conflict("aaaa-1", servers, devices, overrides, nil)
+		So(reason, ShouldEqual, "")
+		So(claimants, ShouldResemble, []int{1})
+	})
+	Convey("Should find a UUID that more than one host reports", t, func() {
+		hosts := []Device{devices[0], {UID: "u3", UUID: "aaaa-1", Host: "esx2"}}
+		_, reporters, reason := Conflict("aaaa-1", servers[:1], hosts, nil, nil)
+		So(reason, ShouldEqual, ConflictDevices)
+		So(reporters, ShouldResemble, []int{0, 1})
+	})
+	Convey("Should not count the same host discovered twice, or excluded and ignored devices", t, func() {
+		_, _, reason := Conflict("aaaa-1", servers[:1], devices, nil, nil)
+		So(reason, ShouldEqual, "")
+		hosts := []Device{devices[0], {UID: "u3", UUID: "aaaa-1", Host: "esx2"}, {UID: "u4", UUID: "aaaa-1", Host: "esx3", Ignore: true}}
+		_, reporters, reason := Conflict("aaaa-1", servers[:1], hosts, []Override{{UID: "u3", Exclude: true}}, nil)
+		So(reason, ShouldEqual, "")
+		So(reporters, ShouldResemble, []int{0})
+	})
+}
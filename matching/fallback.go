@@ -0,0 +1,65 @@
+package matching
+
+import (
+	"strings"
+)
+
+// Device is a host reported by UCS Performance Manager as far as matching
+// goes. Host is its short host name.
+type Device struct {
+	UID    string
+	UUID   string
+	Host   string
+	Serial string
+	Ignore bool
+}
+
+// ShortName returns a host or service profile name without its domain.
+func ShortName(name string) string {
+	name = strings.TrimSpace(name)
+	if pos := strings.Index(name, "."); pos > 0 {
+		name = name[:pos]
+	}
+	return name
+}
+
+// FallbackStrategy returns the first of strategies that ties server to dev by
+// serial or by service profile name, or an empty string.
+func FallbackStrategy(server Server, dev Device, strategies []string) string {
+	for i := 0; i < len(strategies); i++ {
+		switch strategies[i] {
+		case StrategySerial:
+			serial := strings.TrimSpace(dev.Serial)
+			if serial != "" && strings.EqualFold(serial, strings.TrimSpace(server.Serial)) {
+				return StrategySerial
+			}
+		case StrategyHostname:
+			host := ShortName(dev.Host)
+			if host != "" && strings.EqualFold(host, ShortName(server.Profile)) {
+				return StrategyHostname
+			}
+		}
+	}
+	return ""
+}
+
+// Fallback returns the positions in servers, out of those given, of the
+// unclaimed servers that allowed accepts and that dev fits by a fallback
+// strategy. When more than one fits, the match is ambiguous and the reason
+// ConflictFallback is returned with them.
+func Fallback(servers []Server, positions []int, dev Device, strategies []string, allowed func(Server) bool) ([]int, string) {
+	fits := []int{}
+	for i := 0; i < len(positions); i++ {
+		server := servers[positions[i]]
+		if server.Claimed || (allowed != nil && !allowed(server)) {
+			continue
+		}
+		if FallbackStrategy(server, dev, strategies) != "" {
+			fits = append(fits, positions[i])
+		}
+	}
+	if len(fits) > 1 {
+		return fits, ConflictFallback
+	}
+	return fits, ""
+}
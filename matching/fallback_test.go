@@ -0,0 +1,54 @@
+package matching
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_FallbackStrategy(t *testing.T) {
+	server := Server{Serial: " FCH1 ", Profile: "esx1.example.com"}
+	both := []string{StrategyHostname, StrategySerial}
+	Convey("Should tie a server to a device by serial or service profile name", t, func() {
+		So(FallbackStrategy(server, Device{Serial: "fch1"}, both), ShouldEqual, StrategySerial)
+		So(FallbackStrategy(server, Device{Host: "ESX1"}, both), ShouldEqual, StrategyHostname)
+		So(FallbackStrategy(server, Device{Host: "esx2", Serial: "FCH2"}, both), ShouldEqual, "")
+	})
+	Convey("Should try the strategies in the order given, and only those", t, func() {
+		So(FallbackStrategy(server, Device{Host: "esx1", Serial: "FCH1"}, both), ShouldEqual, StrategyHostname)
+		So(FallbackStrategy(server, Device{Host: "esx1"}, []string{StrategySerial}), ShouldEqual, "")
+	})
+}
+
+func Test_Fallback(t *testing.T) {
+	servers := []Server{{Serial: "FCH1", Domain: "dc1"}, {Serial: "FCH1", Domain: "dc2"}, {Serial: "FCH2"}}
+	dev := Device{Serial: "FCH1"}
+	strategies := []string{StrategySerial}
+	Convey("Should match the only server a device fits", t, func() {
+		fits, reason := Fallback(servers, []int{2}, Device{Serial: "FCH2"}, strategies, nil)
+		So(fits, ShouldResemble, []int{2})
+		So(reason, ShouldEqual, "")
+	})
+	Convey("Should report an ambiguous fallback when more than one server fits", t, func() {
+		fits, reason := Fallback(servers, []int{0, 1}, dev, strategies, nil)
+		So(fits, ShouldResemble, []int{0, 1})
+		So(reason, ShouldEqual, ConflictFallback)
+	})
+	Convey("Should leave out claimed servers and servers an override keeps out", t, func() {
+		claimed := []Server{{Serial: "FCH1", Claimed: true}, servers[1]}
+		fits, reason := Fallback(claimed, []int{0, 1}, dev, strategies, nil)
+		So(fits, ShouldResemble, []int{1})
+		So(reason, ShouldEqual, "")
+		allowed := func(server Server) bool { return server.Domain == "dc1" }
+		fits, reason = Fallback(servers, []int{0, 1}, dev, strategies, allowed)
+		So(fits, ShouldResemble, []int{0})
+		So(reason, ShouldEqual, "")
+	})
+}
+
+func Test_ShortName(t *testing.T) {
+	Convey("Should drop the domain from a host name", t, func() {
+		So(ShortName(" esx1.example.com "), ShouldEqual, "esx1")
+		So(ShortName("esx1"), ShouldEqual, "esx1")
+	})
+}
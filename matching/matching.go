@@ -0,0 +1,176 @@
+// Package matching finds the UCS servers that a UUID reported by UCS
+// Performance Manager belongs to, using hash indexes rather than comparing
+// every server with every UUID.
+package matching
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	StrategyUUID         = "uuid"
+	StrategyOriginalUUID = "originaluuid"
+	StrategyTransposed   = "transposed"
+	StrategySerial       = "serial"
+	StrategyHostname     = "hostname"
+)
+
+// Server is a UCS server as far as matching goes. Claimed is set once a UUID
+// has been matched to it.
+type Server struct {
+	UUID         string
+	OriginalUUID string
+	Serial       string
+	Profile      string
+	Domain       string
+	Claimed      bool
+}
+
+// Candidate is a position in the indexed UUID list that a server matches, and
+// the strategy it matched by.
+type Candidate struct {
+	Position int
+	Strategy string
+}
+
+// Normalise returns the form a key is indexed and compared in: trimmed, lower
+// case, and without braces or a urn:uuid: prefix.
+func Normalise(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	key = strings.TrimPrefix(key, "urn:uuid:")
+	key = strings.TrimSuffix(strings.TrimPrefix(key, "{"), "}")
+	return key
+}
+
+// Transpose swaps the byte order of the first three groups of a UUID, which is
+// how some hypervisors report the SMBIOS UUID that UCS Manager assigned.
+func Transpose(uuid string) string {
+	splits := strings.Split(uuid, "-")
+	if len(splits) == 5 {
+		splits[0] = rotateNumber(splits[0])
+		splits[1] = rotateNumber(splits[1])
+		splits[2] = rotateNumber(splits[2])
+		uuid = splits[0] + "-" + splits[1] + "-" + splits[2] + "-" + splits[3] + "-" + splits[4]
+	}
+	return uuid
+}
+
+func rotateNumber(number string) string {
+	if len(number) == 4 {
+		part1 := number[0:2]
+		part2 := number[2:4]
+		number = part2 + part1
+	} else if len(number) == 8 {
+		part1 := number[0:2]
+		part2 := number[2:4]
+		part3 := number[4:6]
+		part4 := number[6:8]
+		number = part4 + part3 + part2 + part1
+	}
+	return number
+}
+
+// Index maps normalised keys to the positions they were added at, in the
+// order they were added.
+type Index map[string][]int
+
+func NewIndex() Index {
+	return make(Index)
+}
+
+// IndexList indexes every entry of list by its position.
+func IndexList(list []string) Index {
+	index := make(Index, len(list))
+	for i := 0; i < len(list); i++ {
+		index.Add(list[i], i)
+	}
+	return index
+}
+
+// Add records position under key. Empty keys are not indexed, and a position
+// is only recorded once for each key.
+func (x Index) Add(key string, position int) {
+	key = Normalise(key)
+	if key == "" {
+		return
+	}
+	positions := x[key]
+	if len(positions) > 0 && positions[len(positions)-1] == position {
+		return
+	}
+	x[key] = append(positions, position)
+}
+
+func (x Index) Lookup(key string) []int {
+	return x[Normalise(key)]
+}
+
+// First returns the first position added under key, or -1.
+func (x Index) First(key string) int {
+	positions := x.Lookup(key)
+	if len(positions) == 0 {
+		return -1
+	}
+	return positions[0]
+}
+
+// Last returns the last position added under key, or -1.
+func (x Index) Last(key string) int {
+	positions := x.Lookup(key)
+	if len(positions) == 0 {
+		return -1
+	}
+	return positions[len(positions)-1]
+}
+
+// Strategy returns how server matches uuid, trying the UUID, then the
+// original UUID, then the transposed UUID, or an empty string if it does not.
+// enabled may be nil to allow every strategy.
+func Strategy(server Server, uuid string, enabled func(string) bool) string {
+	uuid = Normalise(uuid)
+	if uuid == "" {
+		return ""
+	}
+	if Normalise(server.UUID) == uuid && allowed(enabled, StrategyUUID) {
+		return StrategyUUID
+	} else if Normalise(server.OriginalUUID) == uuid && allowed(enabled, StrategyOriginalUUID) {
+		return StrategyOriginalUUID
+	} else if Transpose(Normalise(server.UUID)) == uuid && allowed(enabled, StrategyTransposed) {
+		return StrategyTransposed
+	}
+	return ""
+}
+
+// Candidates returns the positions in uuids, an index of the UUID list, that
+// server matches, in list order. Each position is the first occurrence of its
+// UUID, and carries the strategy Strategy would choose for it.
+func Candidates(uuids Index, server Server, enabled func(string) bool) []Candidate {
+	keys := []string{Normalise(server.UUID), Normalise(server.OriginalUUID), Transpose(Normalise(server.UUID))}
+	candidates := []Candidate{}
+	for i := 0; i < len(keys); i++ {
+		position := uuids.First(keys[i])
+		if position < 0 || hasPosition(candidates, position) {
+			continue
+		}
+		strategy := Strategy(server, keys[i], enabled)
+		if strategy != "" {
+			candidates = append(candidates, Candidate{Position: position, Strategy: strategy})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Position < candidates[j].Position })
+	return candidates
+}
+
+func hasPosition(candidates []Candidate, position int) bool {
+	for i := 0; i < len(candidates); i++ {
+		if candidates[i].Position == position {
+			return true
+		}
+	}
+	return false
+}
+
+func allowed(enabled func(string) bool, strategy string) bool {
+	return enabled == nil || enabled(strategy)
+}
@@ -0,0 +1,126 @@
+package matching
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_Normalise(t *testing.T) {
+	Convey("Should compare UUIDs regardless of case and decoration", t, func() {
+		So(Normalise(" 4C4C4544-0032-4D10 "), ShouldEqual, "4c4c4544-0032-4d10")
+		So(Normalise("{4c4c4544-0032-4d10}"), ShouldEqual, "4c4c4544-0032-4d10")
+		So(Normalise("urn:uuid:4C4C4544-0032-4D10"), ShouldEqual, "4c4c4544-0032-4d10")
+	})
+}
+
+func Test_Transpose(t *testing.T) {
+	Convey("Should swap the byte order of the first three groups", t, func() {
+		So(Transpose("11223344-5566-7788-9900-aabbccddeeff"), ShouldEqual, "44332211-6655-8877-9900-aabbccddeeff")
+	})
+	Convey("Should leave anything that is not a UUID alone", t, func() {
+		So(Transpose("not-a-uuid"), ShouldEqual, "not-a-uuid")
+	})
+}
+
+func Test_Index(t *testing.T) {
+	index := IndexList([]string{"A-1", "b-2", "", "a-1"})
+	Convey("Should find every position of a key", t, func() {
+		So(index.Lookup("a-1"), ShouldResemble, []int{0, 3})
+		So(index.First("A-1"), ShouldEqual, 0)
+		So(index.Last("A-1"), ShouldEqual, 3)
+	})
+	Convey("Should not index empty keys", t, func() {
+		So(index.First(""), ShouldEqual, -1)
+		So(index.First("c-3"), ShouldEqual, -1)
+	})
+}
+
+func Test_Candidates(t *testing.T) {
+	uuids := IndexList([]string{"44332211-6655-8877-9900-aabbccddeeff", "original", "11223344-5566-7788-9900-aabbccddeeff"})
+	server := Server{UUID: "11223344-5566-7788-9900-AABBCCDDEEFF", OriginalUUID: "ORIGINAL"}
+	Convey("Should return every match in list order", t, func() {
+		So(Candidates(uuids, server, nil), ShouldResemble, []Candidate{{0, StrategyTransposed}, {1, StrategyOriginalUUID}, {2, StrategyUUID}})
+	})
+	Convey("Should only use enabled strategies", t, func() {
+		enabled := func(strategy string) bool { return strategy == StrategyUUID }
+		So(Candidates(uuids, server, enabled), ShouldResemble, []Candidate{{2, StrategyUUID}})
+	})
+	Convey("Should give the same first match as comparing every UUID", t, func() {
+		servers, list := estate(200, 200)
+		index := IndexList(list)
+		for i := 0; i < len(servers); i++ {
+			position, strategy := nestedMatch(servers[i], list)
+			candidates := Candidates(index, servers[i], nil)
+			if position < 0 {
+				So(candidates, ShouldBeEmpty)
+			} else {
+				So(candidates[0], ShouldResemble, Candidate{position, strategy})
+			}
+		}
+	})
+}
+
+// estate builds servers and a UUID list in which a third of the UUIDs match
+// directly, a third by original UUID and a third transposed.
+func estate(servers int, uuids int) ([]Server, []string) {
+	list := make([]string, uuids)
+	result := make([]Server, servers)
+	for i := 0; i < servers; i++ {
+		result[i] = Server{UUID: fmt.Sprintf("%08x-0000-1111-2222-%012x", i, i), OriginalUUID: fmt.Sprintf("%08x-ffff-1111-2222-%012x", i, i)}
+	}
+	for i := 0; i < uuids; i++ {
+		server := result[(i*7)%servers]
+		switch i % 3 {
+		case 0:
+			list[i] = server.UUID
+		case 1:
+			list[i] = server.OriginalUUID
+		default:
+			list[i] = Transpose(server.UUID)
+		}
+	}
+	return result, list
+}
+
+// nestedMatch is the comparison of every server with every UUID that the
+// index replaces, kept as the reference for results and speed.
+func nestedMatch(server Server, list []string) (int, string) {
+	for j := 0; j < len(list); j++ {
+		if server.UUID == list[j] {
+			return j, StrategyUUID
+		} else if server.OriginalUUID == list[j] {
+			return j, StrategyOriginalUUID
+		} else if Transpose(server.UUID) == list[j] {
+			return j, StrategyTransposed
+		}
+	}
+	return -1, ""
+}
+
+func benchmarkNested(b *testing.B, size int) {
+	servers, list := estate(size, size)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < len(servers); i++ {
+			nestedMatch(servers[i], list)
+		}
+	}
+}
+
+func benchmarkIndex(b *testing.B, size int) {
+	servers, list := estate(size, size)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		index := IndexList(list)
+		for i := 0; i < len(servers); i++ {
+			Candidates(index, servers[i], nil)
+		}
+	}
+}
+
+// The nested comparison is not run at 10k, where it takes tens of seconds.
+func BenchmarkNested1k(b *testing.B) { benchmarkNested(b, 1000) }
+func BenchmarkIndex1k(b *testing.B)  { benchmarkIndex(b, 1000) }
+func BenchmarkIndex10k(b *testing.B) { benchmarkIndex(b, 10000) }
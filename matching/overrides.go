@@ -0,0 +1,144 @@
+package matching
+
+import (
+	"strings"
+)
+
+const (
+	OverrideApplied  = "applied"
+	OverrideMatched  = "matched"
+	OverrideExcluded = "excluded"
+	OverrideNoDevice = "stale: no device with this uuid or uid"
+	OverrideNoServer = "stale: no unmatched server with this serial"
+)
+
+// Override is a manual decision about a UUID, or about the UUID of the device
+// with a UID. Resolved is the UUID it was found to refer to.
+type Override struct {
+	UUID     string
+	UID      string
+	Serial   string
+	Domain   string
+	Exclude  bool
+	Resolved string
+}
+
+// Resolution is what an override does to the UUID list. UUID is as listed,
+// and Position is where, or -1. Server is the position of the server a serial
+// pins UUID to, or -1. Remove is set when UUID is taken out of automatic
+// matching.
+type Resolution struct {
+	UUID     string
+	Position int
+	Server   int
+	Remove   bool
+	Status   string
+}
+
+// Resolve works out what overrides[i] does to list, indexed as uuids.
+// Excluding one device by UID leaves its UUID to any other device that uses
+// it. A serial pins the UUID to the first unclaimed server with that serial,
+// in the override's domain if it names one.
+func Resolve(overrides []Override, i int, list []string, uuids Index, devices []Device, servers []Server) Resolution {
+	override := overrides[i]
+	result := Resolution{UUID: override.UUID, Position: -1, Server: -1}
+	if override.UID != "" {
+		result.UUID = ""
+		for j := 0; j < len(devices); j++ {
+			if devices[j].UID == override.UID {
+				result.UUID = devices[j].UUID
+				break
+			}
+		}
+	}
+
+	result.Position = uuids.First(result.UUID)
+	if result.Position < 0 {
+		result.Status = OverrideNoDevice
+		return result
+	}
+	result.UUID = list[result.Position]
+
+	if override.Exclude {
+		result.Status = OverrideExcluded
+		result.Remove = override.UID == "" || !shares(overrides, override, result.UUID, devices)
+		return result
+	}
+
+	if override.Serial != "" {
+		for j := 0; j < len(servers); j++ {
+			if servers[j].Claimed || (override.Domain != "" && !strings.EqualFold(override.Domain, servers[j].Domain)) {
+				continue
+			}
+			if strings.EqualFold(override.Serial, strings.TrimSpace(servers[j].Serial)) {
+				result.Server = j
+				break
+			}
+		}
+		if result.Server < 0 {
+			result.Status = OverrideNoServer
+			return result
+		}
+		result.Status = OverrideMatched
+		result.Remove = true
+		return result
+	}
+	result.Status = OverrideApplied
+	return result
+}
+
+// shares reports whether a device other than the one override names by UID
+// still uses uuid.
+func shares(overrides []Override, override Override, uuid string, devices []Device) bool {
+	for i := 0; i < len(devices); i++ {
+		if devices[i].UID == override.UID || devices[i].Ignore || Normalise(devices[i].UUID) != Normalise(uuid) {
+			continue
+		}
+		if !Excludes(overrides, devices[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// ForUUID returns the position of the override that resolved to uuid, or -1.
+func ForUUID(overrides []Override, uuid string) int {
+	for i := 0; i < len(overrides); i++ {
+		if overrides[i].Resolved != "" && Normalise(overrides[i].Resolved) == Normalise(uuid) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ForDevice returns the position of the override for dev, preferring one for
+// its UID over one for its UUID, or -1.
+func ForDevice(overrides []Override, dev Device) int {
+	for i := 0; i < len(overrides); i++ {
+		if overrides[i].UID != "" && overrides[i].UID == dev.UID {
+			return i
+		}
+	}
+	for i := 0; i < len(overrides); i++ {
+		if overrides[i].UID == "" && overrides[i].UUID != "" && Normalise(overrides[i].UUID) == Normalise(dev.UUID) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Excludes reports whether an override excludes dev.
+func Excludes(overrides []Override, dev Device) bool {
+	i := ForDevice(overrides, dev)
+	return i > -1 && overrides[i].Exclude
+}
+
+// Allows reports whether the automatic strategies may match uuid to server,
+// which an override limits to the server's domain.
+func Allows(overrides []Override, server Server, uuid string) bool {
+	i := ForUUID(overrides, uuid)
+	if i < 0 || overrides[i].Domain == "" {
+		return true
+	}
+	return strings.EqualFold(overrides[i].Domain, server.Domain)
+}
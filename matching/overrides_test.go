@@ -0,0 +1,68 @@
+package matching
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_Resolve(t *testing.T) {
+	list := []string{"AAAA-1", "bbbb-2"}
+	uuids := IndexList(list)
+	devices := []Device{{UID: "u1", UUID: "aaaa-1", Host: "esx1"}, {UID: "u2", UUID: "aaaa-1", Host: "esx2"}, {UID: "u3", UUID: "bbbb-2", Host: "esx3"}}
+	servers := []Server{{UUID: "s-0", Serial: "FCH1", Domain: "dc1", Claimed: true}, {UUID: "s-1", Serial: "FCH1", Domain: "dc1"}, {UUID: "s-2", Serial: "FCH1 ", Domain: "DC2"}}
+
+	Convey("Should exclude a UUID named directly", t, func() {
+		result := Resolve([]Override{{UUID: "{aaaa-1}", Exclude: true}}, 0, list, uuids, devices, servers)
+		So(result, ShouldResemble, Resolution{UUID: "AAAA-1", Position: 0, Server: -1, Remove: true, Status: OverrideExcluded})
+	})
+	Convey("Should keep a UUID another device shares when one device is excluded by UID", t, func() {
+		result := Resolve([]Override{{UID: "u1", Exclude: true}}, 0, list, uuids, devices, servers)
+		So(result.Status, ShouldEqual, OverrideExcluded)
+		So(result.UUID, ShouldEqual, "AAAA-1")
+		So(result.Remove, ShouldBeFalse)
+	})
+	Convey("Should exclude the UUID once every device that shares it is excluded or ignored", t, func() {
+		overrides := []Override{{UID: "u1", Exclude: true}, {UID: "u2", Exclude: true}}
+		So(Resolve(overrides, 0, list, uuids, devices, servers).Remove, ShouldBeTrue)
+		ignored := []Device{devices[0], {UID: "u2", UUID: "aaaa-1", Ignore: true}}
+		So(Resolve(overrides[:1], 0, list, uuids, ignored, servers).Remove, ShouldBeTrue)
+	})
+	Convey("Should pin a UUID to the unclaimed server with the serial in the domain", t, func() {
+		So(Resolve([]Override{{UUID: "bbbb-2", Serial: "fch1"}}, 0, list, uuids, devices, servers).Server, ShouldEqual, 1)
+		result := Resolve([]Override{{UID: "u3", Serial: "FCH1", Domain: "dc2"}}, 0, list, uuids, devices, servers)
+		So(result, ShouldResemble, Resolution{UUID: "bbbb-2", Position: 1, Server: 2, Remove: true, Status: OverrideMatched})
+	})
+	Convey("Should report overrides that no longer match anything", t, func() {
+		So(Resolve([]Override{{UUID: "cccc-3", Exclude: true}}, 0, list, uuids, devices, servers), ShouldResemble, Resolution{UUID: "cccc-3", Position: -1, Server: -1, Status: OverrideNoDevice})
+		So(Resolve([]Override{{UID: "u9"}}, 0, list, uuids, devices, servers).Status, ShouldEqual, OverrideNoDevice)
+		result := Resolve([]Override{{UUID: "aaaa-1", Serial: "FCH1", Domain: "dc3"}}, 0, list, uuids, devices, servers)
+		So(result.Status, ShouldEqual, OverrideNoServer)
+		So(result.Remove, ShouldBeFalse)
+	})
+	Convey("Should leave a UUID to the automatic strategies when only a domain is given", t, func() {
+		result := Resolve([]Override{{UUID: "aaaa-1", Domain: "dc2"}}, 0, list, uuids, devices, servers)
+		So(result.Status, ShouldEqual, OverrideApplied)
+		So(result.Remove, ShouldBeFalse)
+	})
+}
+
+func Test_Allows(t *testing.T) {
+	overrides := []Override{{UUID: "aaaa-1", Domain: "dc2", Resolved: "AAAA-1"}}
+	Convey("Should limit a UUID to the domain its override names", t, func() {
+		So(Allows(overrides, Server{Domain: "DC2"}, "{aaaa-1}"), ShouldBeTrue)
+		So(Allows(overrides, Server{Domain: "dc1"}, "aaaa-1"), ShouldBeFalse)
+		So(Allows(overrides, Server{Domain: "dc1"}, "bbbb-2"), ShouldBeTrue)
+	})
+}
+
+func Test_ForDevice(t *testing.T) {
+	overrides := []Override{{UUID: "AAAA-1"}, {UID: "u1", Exclude: true}}
+	Convey("Should prefer an override for the UID over one for the UUID", t, func() {
+		So(ForDevice(overrides, Device{UID: "u1", UUID: "aaaa-1"}), ShouldEqual, 1)
+		So(ForDevice(overrides, Device{UID: "u2", UUID: "aaaa-1"}), ShouldEqual, 0)
+		So(ForDevice(overrides, Device{UID: "u3", UUID: "bbbb-2"}), ShouldEqual, -1)
+		So(Excludes(overrides, Device{UID: "u1"}), ShouldBeTrue)
+		So(Excludes(overrides, Device{UID: "u2", UUID: "aaaa-1"}), ShouldBeFalse)
+	})
+}
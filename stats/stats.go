@@ -0,0 +1,166 @@
+// Package stats summarises a time series of CPU utilisation samples.
+package stats
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Sample is a utilisation percentage recorded at a unix time.
+type Sample struct {
+	Unix  int64
+	Value float64
+}
+
+// Series is a run of samples sorted by time.
+type Series []Sample
+
+func (s Series) values() []float64 {
+	values := make([]float64, 0, len(s))
+	for _, r := range s {
+		values = append(values, r.Value)
+	}
+	return values
+}
+
+func (s Series) Min() float64 {
+	if len(s) == 0 {
+		return 0
+	}
+	min := s[0].Value
+	for _, r := range s {
+		if r.Value < min {
+			min = r.Value
+		}
+	}
+	return min
+}
+
+func (s Series) Max() float64 {
+	if len(s) == 0 {
+		return 0
+	}
+	max := s[0].Value
+	for _, r := range s {
+		if r.Value > max {
+			max = r.Value
+		}
+	}
+	return max
+}
+
+func (s Series) Mean() float64 {
+	if len(s) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, r := range s {
+		total += r.Value
+	}
+	return total / float64(len(s))
+}
+
+// Percentile uses the nearest-rank method, so the result is always one of the
+// recorded samples.
+func (s Series) Percentile(p float64) float64 {
+	if len(s) == 0 {
+		return 0
+	}
+	values := s.values()
+	sort.Float64s(values)
+	if p <= 0 {
+		return values[0]
+	}
+	if p >= 100 {
+		return values[len(values)-1]
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(values))))
+	return values[rank-1]
+}
+
+// Step returns the typical time, in seconds, between samples: the median of
+// the gaps between them, or 0 if there are fewer than two.
+func (s Series) Step() float64 {
+	gaps := []float64{}
+	for i := 0; i < len(s)-1; i++ {
+		if gap := float64(s[i+1].Unix - s[i].Unix); gap > 0 {
+			gaps = append(gaps, gap)
+		}
+	}
+	if len(gaps) == 0 {
+		return 0
+	}
+	sort.Float64s(gaps)
+	middle := len(gaps) / 2
+	if len(gaps)%2 == 0 {
+		return (gaps[middle-1] + gaps[middle]) / 2
+	}
+	return gaps[middle]
+}
+
+// missingFactor is how many typical steps the gap after a sample may span
+// before the rest of it is treated as missing data.
+const missingFactor = 2
+
+// Intervals returns how long, in seconds, each sample represents. A sample
+// holds until the next one arrives, so unevenly spaced samples keep their
+// real weight. A gap longer than missingFactor steps is missing data rather
+// than time spent at the last value seen, and the sample before it is given
+// one step. The final sample is also given one step.
+func (s Series) Intervals() []float64 {
+	step := s.Step()
+	intervals := make([]float64, len(s))
+	for i := 0; i < len(s)-1; i++ {
+		gap := float64(s[i+1].Unix - s[i].Unix)
+		if gap > missingFactor*step {
+			gap = step
+		}
+		if gap > 0 {
+			intervals[i] = gap
+		}
+	}
+	if len(s) > 1 {
+		intervals[len(s)-1] = step
+	}
+	return intervals
+}
+
+// WeightedMean weights each sample by its interval, falling back to the plain
+// mean when no sample has one.
+func (s Series) WeightedMean() float64 {
+	intervals := s.Intervals()
+	total := 0.0
+	weight := 0.0
+	for i, r := range s {
+		total += r.Value * intervals[i]
+		weight += intervals[i]
+	}
+	if weight == 0 {
+		return s.Mean()
+	}
+	return total / weight
+}
+
+// BurstDuration is the time spent above threshold.
+func (s Series) BurstDuration(threshold float64) time.Duration {
+	intervals := s.Intervals()
+	seconds := 0.0
+	for i, r := range s {
+		if r.Value > threshold {
+			seconds += intervals[i]
+		}
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// CoreHours converts the utilisation percentages into the number of fully
+// busy core-hours consumed across the window.
+func (s Series) CoreHours(cores int) float64 {
+	intervals := s.Intervals()
+	total := 0.0
+	for i, r := range s {
+		total += r.Value / 100 * intervals[i] / 3600
+	}
+	return total * float64(cores)
+}
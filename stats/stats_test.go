@@ -0,0 +1,108 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// series builds samples at the given offsets, in seconds, from a fixed time.
+func series(offsets []int64, values []float64) Series {
+	s := make(Series, len(offsets))
+	for i := 0; i < len(offsets); i++ {
+		s[i] = Sample{Unix: 1500000000 + offsets[i], Value: values[i]}
+	}
+	return s
+}
+
+var cases = []struct {
+	name      string
+	series    Series
+	step      float64
+	p50       float64
+	p95       float64
+	weighted  float64
+	burst     time.Duration
+	coreHours float64
+	intervals []float64
+}{
+	{
+		name:      "even spacing",
+		series:    series([]int64{0, 300, 600, 900}, []float64{10, 20, 90, 40}),
+		step:      300,
+		p50:       20,
+		p95:       90,
+		weighted:  40,
+		burst:     300 * time.Second,
+		coreHours: 0.26666667,
+		intervals: []float64{300, 300, 300, 300},
+	},
+	{
+		name:      "uneven spacing",
+		series:    series([]int64{0, 300, 360, 900, 1200}, []float64{10, 90, 50, 20, 100}),
+		step:      300,
+		p50:       50,
+		p95:       100,
+		weighted:  47.6,
+		burst:     360 * time.Second,
+		coreHours: 0.39666667,
+		intervals: []float64{300, 60, 540, 300, 300},
+	},
+	{
+		name:      "mixed downsampling",
+		series:    series([]int64{0, 300, 600, 4200, 7800}, []float64{10, 20, 30, 80, 90}),
+		step:      1950,
+		p50:       30,
+		p95:       90,
+		weighted:  59.53846154,
+		burst:     1950 * time.Second,
+		coreHours: 3.225,
+		intervals: []float64{300, 300, 3600, 3600, 1950},
+	},
+	{
+		name:      "gap in the data",
+		series:    series([]int64{0, 300, 600, 7800, 8100}, []float64{10, 20, 95, 30, 40}),
+		step:      300,
+		p50:       30,
+		p95:       95,
+		weighted:  39,
+		burst:     300 * time.Second,
+		coreHours: 0.325,
+		intervals: []float64{300, 300, 300, 300, 300},
+	},
+	{
+		name:      "single sample",
+		series:    series([]int64{0}, []float64{70}),
+		step:      0,
+		p50:       70,
+		p95:       70,
+		weighted:  70,
+		burst:     0,
+		coreHours: 0,
+		intervals: []float64{0},
+	},
+}
+
+func Test_Series(t *testing.T) {
+	for _, c := range cases {
+		Convey("Should summarise "+c.name, t, func() {
+			So(c.series.Step(), ShouldEqual, c.step)
+			So(c.series.Intervals(), ShouldResemble, c.intervals)
+			So(c.series.Percentile(50), ShouldEqual, c.p50)
+			So(c.series.Percentile(95), ShouldEqual, c.p95)
+			So(c.series.WeightedMean(), ShouldAlmostEqual, c.weighted, 0.000001)
+			So(c.series.BurstDuration(80), ShouldEqual, c.burst)
+			So(c.series.CoreHours(2), ShouldAlmostEqual, c.coreHours, 0.000001)
+		})
+	}
+}
+
+func Test_Empty(t *testing.T) {
+	Convey("Should return zero for an empty series", t, func() {
+		So(Series{}.Percentile(95), ShouldEqual, 0)
+		So(Series{}.WeightedMean(), ShouldEqual, 0)
+		So(Series{}.BurstDuration(80), ShouldEqual, 0)
+		So(Series{}.CoreHours(4), ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,115 @@
+package transport
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProxyFunc returns the proxy selection for opts, honouring the NoProxy
+// exclusions.
+func ProxyFunc(opts Options) (func(*http.Request) (*url.URL, error), error) {
+	proxy := strings.TrimSpace(opts.Proxy)
+	if proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	if strings.ToLower(proxy) == "none" {
+		return nil, nil
+	}
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Host == "" {
+		return nil, errors.New("transport: invalid proxy " + opts.Proxy)
+	}
+	if opts.ProxyUsername != "" {
+		proxyURL.User = url.UserPassword(opts.ProxyUsername, opts.ProxyPassword)
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if BypassProxy(req.URL.Hostname(), opts.NoProxy) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// BypassProxy reports whether host matches one of the NO_PROXY style
+// exclusions. An exclusion may be "*", a host name, a domain suffix such as
+// .example.com, an IP address or a CIDR range.
+func BypassProxy(host string, exclusions []string) bool {
+	host = strings.ToLower(strings.TrimSpace(host))
+	ip := net.ParseIP(host)
+	for _, exclusion := range exclusions {
+		exclusion = strings.ToLower(strings.TrimSpace(exclusion))
+		if exclusion == "" {
+			continue
+		}
+		if exclusion == "*" {
+			return true
+		}
+		if _, network, err := net.ParseCIDR(exclusion); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, _, err := net.SplitHostPort(exclusion); err == nil {
+			exclusion = h
+		}
+		if host == exclusion || host == strings.TrimPrefix(exclusion, ".") {
+			return true
+		}
+		if ip == nil && strings.HasSuffix(host, "."+strings.TrimPrefix(exclusion, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseList splits a comma separated list, such as NO_PROXY exclusions.
+func ParseList(list string) []string {
+	result := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if strings.TrimSpace(item) != "" {
+			result = append(result, strings.TrimSpace(item))
+		}
+	}
+	return result
+}
+
+// sourceAddr resolves an IP address or interface name to the local address
+// to connect from.
+func sourceAddr(source string) (net.Addr, error) {
+	if ip := net.ParseIP(source); ip != nil {
+		return &net.TCPAddr{IP: ip}, nil
+	}
+	iface, err := net.InterfaceByName(source)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	var fallback net.IP
+	for _, addr := range addrs {
+		if network, ok := addr.(*net.IPNet); ok {
+			if network.IP.To4() != nil {
+				return &net.TCPAddr{IP: network.IP}, nil
+			}
+			if fallback == nil {
+				fallback = network.IP
+			}
+		}
+	}
+	if fallback != nil {
+		return &net.TCPAddr{IP: fallback}, nil
+	}
+	return nil, errors.New("transport: no address found on interface " + source)
+}
@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_BypassProxy(t *testing.T) {
+	exclusions := ParseList("10.0.0.0/8, .lab.local, ucspm.example.com, 192.168.1.5")
+	Convey("Should bypass hosts that match an exclusion", t, func() {
+		So(BypassProxy("10.1.2.3", exclusions), ShouldBeTrue)
+		So(BypassProxy("ucs01.lab.local", exclusions), ShouldBeTrue)
+		So(BypassProxy("lab.local", exclusions), ShouldBeTrue)
+		So(BypassProxy("UCSPM.example.com", exclusions), ShouldBeTrue)
+		So(BypassProxy("192.168.1.5", exclusions), ShouldBeTrue)
+	})
+	Convey("Should proxy hosts that do not match", t, func() {
+		So(BypassProxy("11.1.2.3", exclusions), ShouldBeFalse)
+		So(BypassProxy("otherlab.local", exclusions), ShouldBeFalse)
+		So(BypassProxy("example.com", exclusions), ShouldBeFalse)
+	})
+	Convey("Should bypass everything for a wildcard", t, func() {
+		So(BypassProxy("anything", []string{"*"}), ShouldBeTrue)
+	})
+}
+
+func Test_Proxy(t *testing.T) {
+	auth := ""
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Proxy-Authorization")
+		w.Write([]byte("proxied " + r.URL.Host))
+	}))
+	defer proxy.Close()
+
+	Convey("Should send requests through an authenticated proxy", t, func() {
+		client, err := NewClient(Options{Proxy: proxy.URL, ProxyUsername: "billing", ProxyPassword: "secret"})
+		So(err, ShouldBeNil)
+		resp, err := client.Get("http://ucs.example.com/nuova")
+		So(err, ShouldBeNil)
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		So(string(body), ShouldEqual, "proxied ucs.example.com")
+		So(auth, ShouldStartWith, "Basic ")
+	})
+	Convey("Should reject an invalid proxy", t, func() {
+		_, err := NewClient(Options{Proxy: "http://"})
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Should connect directly when the proxy is none", t, func() {
+		proxyFunc, err := ProxyFunc(Options{Proxy: "none"})
+		So(err, ShouldBeNil)
+		So(proxyFunc, ShouldBeNil)
+	})
+}
+
+func Test_Source(t *testing.T) {
+	Convey("Should accept a source IP address", t, func() {
+		addr, err := sourceAddr("127.0.0.1")
+		So(err, ShouldBeNil)
+		So(addr.String(), ShouldEqual, "127.0.0.1:0")
+	})
+	Convey("Should reject an unknown interface", t, func() {
+		_, err := sourceAddr("nosuchinterface0")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,153 @@
+package transport
+
+import (
+	"crypto/x509"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how often an idempotent request is tried. The zero
+// value tries once.
+type RetryPolicy struct {
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Backoff returns the wait before the given retry, doubling from BaseDelay up
+// to MaxDelay, with jitter so that parallel workers do not retry in step.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	if p.BaseDelay <= 0 || retry < 1 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// Retryable reports whether a request that ended with code and err is worth
+// trying again: network errors and timeouts, throttling and gateway errors.
+// A certificate that fails verification or pinning fails the same way every
+// time, so it is not retried.
+func Retryable(code int, err error) bool {
+	if err != nil {
+		return !CertificateError(err)
+	}
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// CertificateError reports whether err is a failure to verify or pin the
+// server's certificate.
+func CertificateError(err error) bool {
+	if errors.Is(err, ErrPinMismatch) {
+		return true
+	}
+	var unknown x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	return errors.As(err, &unknown) || errors.As(err, &hostname) || errors.As(err, &invalid)
+}
+
+// Run calls attempt until it succeeds or returns something not worth
+// retrying, or the policy runs out of attempts. Requests that are not
+// idempotent are only tried once. The breaker, if set, is consulted before
+// every attempt and told the outcome, except for certificate errors, which
+// say nothing about the endpoint's health.
+func (p RetryPolicy) Run(breaker *Breaker, endpoint string, idempotent bool, attempt func(n int) (int, error)) (int, error) {
+	attempts := 1
+	if idempotent && p.Attempts > 1 {
+		attempts = p.Attempts
+	}
+	code := 0
+	var err error
+	for n := 1; n <= attempts; n++ {
+		if n > 1 {
+			time.Sleep(p.Backoff(n - 1))
+		}
+		if err := breaker.Allow(endpoint); err != nil {
+			return 0, err
+		}
+		code, err = attempt(n)
+		if CertificateError(err) {
+			return code, err
+		} else if Retryable(code, err) {
+			breaker.Failure(endpoint)
+		} else {
+			breaker.Success(endpoint)
+			return code, err
+		}
+	}
+	return code, err
+}
+
+// CircuitOpenError is returned while an endpoint's circuit is open.
+type CircuitOpenError struct {
+	Endpoint string
+	Until    time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return "transport: circuit open for " + e.Endpoint + " until " + e.Until.Format(time.RFC3339)
+}
+
+// Breaker stops calls to an endpoint for Cooldown after Threshold failures in
+// a row. After the cooldown one failure opens it again. A nil Breaker allows
+// everything.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+	lock      sync.Mutex
+	failures  map[string]int
+	openUntil map[string]time.Time
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{Threshold: threshold, Cooldown: cooldown, failures: make(map[string]int), openUntil: make(map[string]time.Time)}
+}
+
+func (b *Breaker) Allow(endpoint string) error {
+	if b == nil || b.Threshold < 1 {
+		return nil
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if until, ok := b.openUntil[endpoint]; ok && time.Now().Before(until) {
+		return &CircuitOpenError{Endpoint: endpoint, Until: until}
+	}
+	return nil
+}
+
+func (b *Breaker) Success(endpoint string) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.failures, endpoint)
+	delete(b.openUntil, endpoint)
+}
+
+func (b *Breaker) Failure(endpoint string) {
+	if b == nil || b.Threshold < 1 {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.failures[endpoint]++
+	if b.failures[endpoint] >= b.Threshold {
+		b.openUntil[endpoint] = time.Now().Add(b.Cooldown)
+	}
+}
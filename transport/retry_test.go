@@ -0,0 +1,107 @@
+package transport
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_Backoff(t *testing.T) {
+	policy := RetryPolicy{Attempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	Convey("Should not wait before the first attempt", t, func() {
+		So(policy.Backoff(0), ShouldEqual, 0)
+	})
+	Convey("Should double the delay with jitter", t, func() {
+		So(policy.Backoff(1), ShouldBeBetweenOrEqual, 50*time.Millisecond, 100*time.Millisecond)
+		So(policy.Backoff(2), ShouldBeBetweenOrEqual, 100*time.Millisecond, 200*time.Millisecond)
+	})
+	Convey("Should not exceed the maximum delay", t, func() {
+		So(policy.Backoff(10), ShouldBeLessThanOrEqualTo, 300*time.Millisecond)
+	})
+}
+
+func Test_Retryable(t *testing.T) {
+	Convey("Should retry network errors and gateway responses", t, func() {
+		So(Retryable(0, errors.New("timeout")), ShouldBeTrue)
+		So(Retryable(429, nil), ShouldBeTrue)
+		So(Retryable(503, nil), ShouldBeTrue)
+	})
+	Convey("Should not retry other responses", t, func() {
+		So(Retryable(200, nil), ShouldBeFalse)
+		So(Retryable(401, nil), ShouldBeFalse)
+		So(Retryable(500, nil), ShouldBeFalse)
+	})
+	Convey("Should not retry certificate and pin verification errors", t, func() {
+		So(Retryable(0, &url.Error{Op: "Get", URL: "https://ucs", Err: ErrPinMismatch}), ShouldBeFalse)
+		So(Retryable(0, &url.Error{Op: "Get", URL: "https://ucs", Err: x509.UnknownAuthorityError{}}), ShouldBeFalse)
+		So(Retryable(0, x509.HostnameError{Host: "ucs"}), ShouldBeFalse)
+	})
+}
+
+func Test_Run(t *testing.T) {
+	policy := RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond}
+	Convey("Should retry an idempotent request until it succeeds", t, func() {
+		calls := 0
+		code, err := policy.Run(nil, "ucs", true, func(n int) (int, error) {
+			calls = n
+			if n < 3 {
+				return 503, nil
+			}
+			return 200, nil
+		})
+		So(err, ShouldBeNil)
+		So(code, ShouldEqual, 200)
+		So(calls, ShouldEqual, 3)
+	})
+	Convey("Should only try a request that is not idempotent once", t, func() {
+		calls := 0
+		_, err := policy.Run(nil, "ucs", false, func(n int) (int, error) {
+			calls++
+			return 0, errors.New("reset")
+		})
+		So(err, ShouldNotBeNil)
+		So(calls, ShouldEqual, 1)
+	})
+}
+
+func Test_Breaker(t *testing.T) {
+	Convey("Should open after the threshold and close after the cooldown", t, func() {
+		breaker := NewBreaker(2, 20*time.Millisecond)
+		breaker.Failure("ucs")
+		So(breaker.Allow("ucs"), ShouldBeNil)
+		breaker.Failure("ucs")
+		So(breaker.Allow("ucs"), ShouldNotBeNil)
+		So(breaker.Allow("ucspm"), ShouldBeNil)
+		time.Sleep(30 * time.Millisecond)
+		So(breaker.Allow("ucs"), ShouldBeNil)
+		breaker.Success("ucs")
+		breaker.Failure("ucs")
+		So(breaker.Allow("ucs"), ShouldBeNil)
+	})
+	Convey("Should stop retries while the circuit is open", t, func() {
+		breaker := NewBreaker(1, time.Minute)
+		calls := 0
+		_, err := RetryPolicy{Attempts: 3}.Run(breaker, "ucs", true, func(n int) (int, error) {
+			calls++
+			return 502, nil
+		})
+		_, open := err.(*CircuitOpenError)
+		So(open, ShouldBeTrue)
+		So(calls, ShouldEqual, 1)
+	})
+	Convey("Should not count certificate errors as failures", t, func() {
+		breaker := NewBreaker(1, time.Minute)
+		calls := 0
+		_, err := RetryPolicy{Attempts: 3}.Run(breaker, "ucs", true, func(n int) (int, error) {
+			calls++
+			return 0, &url.Error{Op: "Get", URL: "https://ucs", Err: ErrPinMismatch}
+		})
+		So(errors.Is(err, ErrPinMismatch), ShouldBeTrue)
+		So(calls, ShouldEqual, 1)
+		So(breaker.Allow("ucs"), ShouldBeNil)
+	})
+}
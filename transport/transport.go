@@ -0,0 +1,175 @@
+// Package transport builds the HTTP clients used to talk to UCS Manager and
+// UCS Performance Manager, with certificate verification turned on unless a
+// system explicitly opts out.
+package transport
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Options describes how to connect to a single system. Proxy may include a
+// username and password; an empty Proxy falls back to the HTTP_PROXY
+// environment variables and "none" connects directly. Source is the local
+// address or interface name to connect from. A request that takes longer
+// than ConnectTimeout and ReadTimeout together is abandoned.
+type Options struct {
+	CAFile         string
+	Pins           []string
+	Insecure       bool
+	Proxy          string
+	ProxyUsername  string
+	ProxyPassword  string
+	NoProxy        []string
+	Source         string
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+}
+
+const (
+	defaultConnectTimeout = 30 * time.Second
+	defaultReadTimeout    = 120 * time.Second
+)
+
+// NewClient returns an HTTP client that verifies the server certificate
+// according to opts.
+func NewClient(opts Options) (*http.Client, error) {
+	config, err := TLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := ProxyFunc(opts)
+	if err != nil {
+		return nil, err
+	}
+	connect := opts.ConnectTimeout
+	if connect <= 0 {
+		connect = defaultConnectTimeout
+	}
+	read := opts.ReadTimeout
+	if read <= 0 {
+		read = defaultReadTimeout
+	}
+	dialer := &net.Dialer{
+		Timeout:   connect,
+		KeepAlive: 30 * time.Second,
+	}
+	if opts.Source != "" {
+		dialer.LocalAddr, err = sourceAddr(opts.Source)
+		if err != nil {
+			return nil, err
+		}
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       config,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   connect,
+		ResponseHeaderTimeout: read,
+	}
+	return &http.Client{Transport: transport, Timeout: connect + read}, nil
+}
+
+// TLSConfig returns the TLS settings for opts. With a CA bundle the chain is
+// verified against it rather than the system roots. Pins are checked against
+// the SHA-256 fingerprint of the presented certificates; when pins are given
+// without a CA bundle the pin alone is trusted, which suits self-signed
+// appliance certificates.
+func TLSConfig(opts Options) (*tls.Config, error) {
+	config := &tls.Config{}
+	if opts.Insecure {
+		config.InsecureSkipVerify = true
+		return config, nil
+	}
+	if opts.CAFile != "" {
+		pem, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("transport: no certificates found in " + opts.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	pins := []string{}
+	for i := 0; i < len(opts.Pins); i++ {
+		if pin := NormalisePin(opts.Pins[i]); pin != "" {
+			pins = append(pins, pin)
+		}
+	}
+	if len(pins) > 0 {
+		if opts.CAFile == "" {
+			// Without a CA nothing ties the rest of the chain to the key the
+			// server proved it holds, so only the leaf can be pinned.
+			config.InsecureSkipVerify = true
+			config.VerifyPeerCertificate = func(rawCerts [][]byte, chains [][]*x509.Certificate) error {
+				if len(rawCerts) == 0 {
+					return ErrPinMismatch
+				}
+				return verifyPins(pins, rawCerts[:1])
+			}
+		} else {
+			config.VerifyPeerCertificate = func(rawCerts [][]byte, chains [][]*x509.Certificate) error {
+				for i := 0; i < len(chains); i++ {
+					chain := [][]byte{}
+					for j := 0; j < len(chains[i]); j++ {
+						chain = append(chain, chains[i][j].Raw)
+					}
+					if verifyPins(pins, chain) == nil {
+						return nil
+					}
+				}
+				return ErrPinMismatch
+			}
+		}
+	}
+	return config, nil
+}
+
+// ErrPinMismatch is returned when no pinned fingerprint matches the server.
+var ErrPinMismatch = errors.New("transport: server certificate does not match any pinned fingerprint")
+
+func verifyPins(pins []string, certs [][]byte) error {
+	for i := 0; i < len(certs); i++ {
+		fingerprint := Fingerprint(certs[i])
+		for j := 0; j < len(pins); j++ {
+			if fingerprint == pins[j] {
+				return nil
+			}
+		}
+	}
+	return ErrPinMismatch
+}
+
+// Fingerprint returns the SHA-256 fingerprint of a DER encoded certificate as
+// lower case hex.
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalisePin accepts a fingerprint in the form printed by
+// openssl x509 -fingerprint -sha256, with or without the colons.
+func NormalisePin(pin string) string {
+	pin = strings.TrimSpace(strings.ToLower(pin))
+	pin = strings.TrimPrefix(pin, "sha256:")
+	pin = strings.TrimPrefix(pin, "sha256 fingerprint=")
+	pin = strings.Replace(pin, ":", "", -1)
+	return strings.Replace(pin, " ", "", -1)
+}
+
+// ParsePins splits a comma separated list of fingerprints.
+func ParsePins(pins string) []string {
+	return ParseList(pins)
+}
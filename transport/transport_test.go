@@ -0,0 +1,113 @@
+package transport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newTLSServer() *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+}
+
+// newAttackerServer presents its own leaf, followed by the pinned certificate
+// it does not hold the key for.
+func newAttackerServer(pinned []byte) *httptest.Server {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "attacker"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	leaf, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{leaf, pinned}, PrivateKey: key}}}
+	server.StartTLS()
+	return server
+}
+
+func get(opts Options, url string) error {
+	client, err := NewClient(opts)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Get(url)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return err
+}
+
+func Test_NormalisePin(t *testing.T) {
+	Convey("Should accept openssl style fingerprints", t, func() {
+		So(NormalisePin("SHA256 Fingerprint=AB:CD:EF"), ShouldEqual, "abcdef")
+		So(NormalisePin(" ab:cd:ef "), ShouldEqual, "abcdef")
+	})
+	Convey("Should split a list of pins", t, func() {
+		So(ParsePins("aa:bb, cc:dd,"), ShouldResemble, []string{"aa:bb", "cc:dd"})
+		So(ParsePins(""), ShouldBeEmpty)
+	})
+}
+
+func Test_Verification(t *testing.T) {
+	server := newTLSServer()
+	defer server.Close()
+	der := server.Certificate().Raw
+
+	Convey("Should reject an unknown self-signed certificate by default", t, func() {
+		So(get(Options{}, server.URL), ShouldNotBeNil)
+	})
+	Convey("Should accept a certificate that matches a pin", t, func() {
+		So(get(Options{Pins: []string{Fingerprint(der)}}, server.URL), ShouldBeNil)
+	})
+	Convey("Should reject a certificate that does not match a pin", t, func() {
+		So(get(Options{Pins: []string{"00:11:22"}}, server.URL), ShouldNotBeNil)
+	})
+	Convey("Should only pin the leaf the server holds the key for", t, func() {
+		attacker := newAttackerServer(der)
+		defer attacker.Close()
+		So(get(Options{Pins: []string{Fingerprint(der)}}, attacker.URL), ShouldNotBeNil)
+	})
+	Convey("Should check pins against the verified chain when a CA is used", t, func() {
+		file, _ := ioutil.TempFile("", "ca")
+		defer os.Remove(file.Name())
+		pem.Encode(file, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+		file.Close()
+		So(get(Options{CAFile: file.Name(), Pins: []string{Fingerprint(der)}}, server.URL), ShouldBeNil)
+		So(get(Options{CAFile: file.Name(), Pins: []string{"00:11:22"}}, server.URL), ShouldNotBeNil)
+	})
+	Convey("Should accept a certificate signed by the CA bundle", t, func() {
+		file, _ := ioutil.TempFile("", "ca")
+		defer os.Remove(file.Name())
+		pem.Encode(file, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+		file.Close()
+		So(get(Options{CAFile: file.Name()}, server.URL), ShouldBeNil)
+	})
+	Convey("Should fail for a missing CA bundle", t, func() {
+		_, err := NewClient(Options{CAFile: "/does/not/exist.pem"})
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Should skip verification only when insecure is set", t, func() {
+		So(get(Options{Insecure: true}, server.URL), ShouldBeNil)
+	})
+}
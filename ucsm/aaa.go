@@ -0,0 +1,71 @@
+package ucsm
+
+import (
+	"encoding/xml"
+	"errors"
+)
+
+type aaaLogin struct {
+	XMLName    xml.Name `xml:"aaaLogin"`
+	InName     string   `xml:"inName,attr"`
+	InPassword string   `xml:"inPassword,attr"`
+}
+
+type aaaRefresh struct {
+	XMLName    xml.Name `xml:"aaaRefresh"`
+	InName     string   `xml:"inName,attr"`
+	InPassword string   `xml:"inPassword,attr"`
+	InCookie   string   `xml:"inCookie,attr"`
+}
+
+type aaaLogout struct {
+	XMLName  xml.Name `xml:"aaaLogout"`
+	InCookie string   `xml:"inCookie,attr"`
+}
+
+// Session is the reply to aaaLogin and aaaRefresh.
+type Session struct {
+	Cookie        string `xml:"outCookie,attr"`
+	RefreshPeriod int    `xml:"outRefreshPeriod,attr"`
+	Priv          string `xml:"outPriv,attr"`
+	Domains       string `xml:"outDomains,attr"`
+	Version       string `xml:"outVersion,attr"`
+	Name          string `xml:"outName,attr"`
+}
+
+type logoutResponse struct {
+	Status string `xml:"outStatus,attr"`
+}
+
+func (c *Client) Login() (*Session, error) {
+	var session Session
+	if err := c.call(aaaLogin{InName: c.Username, InPassword: c.Password}, &session, false); err != nil {
+		return nil, err
+	}
+	if session.Cookie == "" {
+		return nil, errors.New("ucsm: aaaLogin returned no cookie")
+	}
+	c.setSession(session.Cookie, session.Version, session.RefreshPeriod)
+	return &session, nil
+}
+
+func (c *Client) Refresh() (*Session, error) {
+	var session Session
+	if err := c.call(aaaRefresh{InName: c.Username, InPassword: c.Password, InCookie: c.Cookie()}, &session, false); err != nil {
+		return nil, err
+	}
+	if session.Cookie == "" {
+		return nil, errors.New("ucsm: aaaRefresh returned no cookie")
+	}
+	c.setSession(session.Cookie, session.Version, session.RefreshPeriod)
+	return &session, nil
+}
+
+func (c *Client) Logout() error {
+	var response logoutResponse
+	if err := c.call(aaaLogout{InCookie: c.Cookie()}, &response, false); err != nil {
+		return err
+	}
+	c.setSession("", "", 0)
+	return nil
+}
@@ -0,0 +1,150 @@
+// Package ucsm is a client for the Cisco UCS Manager XML API.
+package ucsm
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"../transport"
+)
+
+// SendFunc sends a single request and returns the status code and body.
+type SendFunc func(url string, method string, body string, headers map[string]string) (int, string, error)
+
+// RecordFunc is called after every attempt at a request, whether or not it
+// succeeded. Attempts are numbered from 1.
+type RecordFunc func(url string, body string, headers map[string]string, response string, code int, attempt int, err error)
+
+// Client talks to a single UCS Manager domain. Once logged in it is safe to
+// use from several goroutines at once.
+type Client struct {
+	URL        string
+	Username   string
+	Password   string
+	HTTPClient *http.Client
+	Send       SendFunc
+	Record     RecordFunc
+	Retry      transport.RetryPolicy
+	Breaker    *transport.Breaker
+	lock       sync.RWMutex
+	cookie     string
+	version    string
+	refresh    int
+}
+
+func New(url string, username string, password string) *Client {
+	return &Client{URL: url, Username: username, Password: password}
+}
+
+// Endpoint returns the XML API URL, adding a scheme and the /nuova path if
+// they are missing.
+func (c *Client) Endpoint() string {
+	url := strings.TrimSpace(c.URL)
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		url = "https://" + url
+	}
+	if !strings.HasSuffix(url, "/nuova") {
+		url = strings.TrimRight(url, "/") + "/nuova"
+	}
+	return url
+}
+
+// Cookie returns the session cookie from the last successful login or refresh.
+func (c *Client) Cookie() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.cookie
+}
+
+// Version returns the UCS Manager version reported at login.
+func (c *Client) Version() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.version
+}
+
+// RefreshPeriod returns the number of seconds the session cookie is valid for.
+func (c *Client) RefreshPeriod() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.refresh
+}
+
+func (c *Client) setSession(cookie string, version string, refresh int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.cookie = cookie
+	if version != "" {
+		c.version = version
+	}
+	c.refresh = refresh
+}
+
+func (c *Client) send(url string, body string, headers map[string]string) (int, string, error) {
+	if c.Send != nil {
+		return c.Send(url, "POST", body, headers)
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewBufferString(body))
+	if err != nil {
+		return 0, "", err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(data), err
+}
+
+type errorResponse struct {
+	XMLName    xml.Name
+	ErrorCode  string `xml:"errorCode,attr"`
+	ErrorDescr string `xml:"errorDescr,attr"`
+}
+
+// call posts a single method and decodes the reply into response. Any
+// errorCode in the reply is returned as an *Error. Only idempotent methods are
+// retried.
+func (c *Client) call(request interface{}, response interface{}, idempotent bool) error {
+	body, err := xml.Marshal(request)
+	if err != nil {
+		return err
+	}
+	url := c.Endpoint()
+	headers := map[string]string{"Content-Type": "application/xml"}
+	reply := ""
+	code, err := c.Retry.Run(c.Breaker, url, idempotent, func(attempt int) (int, error) {
+		code, response, err := c.send(url, string(body), headers)
+		reply = response
+		if c.Record != nil {
+			c.Record(url, string(body), headers, response, code, attempt, err)
+		}
+		return code, err
+	})
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return &HTTPError{URL: url, StatusCode: code, Body: reply}
+	}
+	var status errorResponse
+	if err := xml.Unmarshal([]byte(reply), &status); err != nil {
+		return err
+	}
+	if status.ErrorCode != "" {
+		return &Error{Method: status.XMLName.Local, Code: status.ErrorCode, Description: status.ErrorDescr}
+	}
+	return xml.Unmarshal([]byte(reply), response)
+}
@@ -0,0 +1,111 @@
+package ucsm
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newTestServer(handler func(body string) string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte(handler(string(body))))
+	}))
+}
+
+func Test_Endpoint(t *testing.T) {
+	Convey("Should add a scheme and the nuova path", t, func() {
+		So(New("10.0.0.1", "", "").Endpoint(), ShouldEqual, "https://10.0.0.1/nuova")
+	})
+	Convey("Should keep a complete URL", t, func() {
+		So(New("https://ucs.local/nuova", "", "").Endpoint(), ShouldEqual, "https://ucs.local/nuova")
+	})
+}
+
+func Test_Login(t *testing.T) {
+	server := newTestServer(func(body string) string {
+		if strings.Contains(body, `inPassword="secret"`) {
+			return `<aaaLogin cookie="" response="yes" outCookie="1500000000/abc" outRefreshPeriod="600" outPriv="read-only" outVersion="3.1(2b)"></aaaLogin>`
+		}
+		return `<aaaLogin cookie="" response="yes" errorCode="551" invocationResult="unidentified-fail" errorDescr="Authentication failed"></aaaLogin>`
+	})
+	defer server.Close()
+	Convey("Should store the session after login", t, func() {
+		client := New(server.URL, "admin", "secret")
+		_, err := client.Login()
+		So(err, ShouldBeNil)
+		So(client.Cookie(), ShouldEqual, "1500000000/abc")
+		So(client.Version(), ShouldEqual, "3.1(2b)")
+		So(client.RefreshPeriod(), ShouldEqual, 600)
+	})
+	Convey("Should return the UCS error code and description", t, func() {
+		_, err := New(server.URL, "admin", "wrong").Login()
+		ucsErr, ok := err.(*Error)
+		So(ok, ShouldBeTrue)
+		So(ucsErr.Code, ShouldEqual, "551")
+		So(ucsErr.Description, ShouldEqual, "Authentication failed")
+	})
+}
+
+func Test_ServiceProfileOrg(t *testing.T) {
+	Convey("Should return the org path of a service profile", t, func() {
+		So(LsServer{Dn: "org-root/org-Finance/ls-web01"}.Org(), ShouldEqual, "org-root/org-Finance")
+		So(LsServer{Dn: "org-root/ls-esx01"}.Org(), ShouldEqual, "org-root")
+	})
+	Convey("Should return nothing for an unexpected DN", t, func() {
+		So(LsServer{Dn: "sys/chassis-1"}.Org(), ShouldEqual, "")
+	})
+}
+
+func Test_ProcessorUnit(t *testing.T) {
+	Convey("Should return the server a processor is fitted to", t, func() {
+		So(ProcessorUnit{Dn: "sys/chassis-1/blade-2/board/cpu-1"}.ServerDn(), ShouldEqual, "sys/chassis-1/blade-2")
+		So(ProcessorUnit{Dn: "sys/rack-unit-3/board/cpu-2"}.ServerDn(), ShouldEqual, "sys/rack-unit-3")
+	})
+	Convey("Should only count equipped sockets", t, func() {
+		So(ProcessorUnit{Presence: "equipped"}.Equipped(), ShouldBeTrue)
+		So(ProcessorUnit{Presence: "missing"}.Equipped(), ShouldBeFalse)
+	})
+}
+
+func Test_Config(t *testing.T) {
+	server := newTestServer(func(body string) string {
+		switch {
+		case strings.Contains(body, "configResolveClasses"):
+			return `<configResolveClasses cookie="abc" response="yes"><outConfigs><computeBlade dn="sys/chassis-1/blade-1" serial="FCH1234"/><computeRackUnit dn="sys/rack-unit-1" serial="WZP5678"/></outConfigs></configResolveClasses>`
+		case strings.Contains(body, "configFindDnsByClassId"):
+			return `<configFindDnsByClassId cookie="abc" response="yes" classId="computeItem"><outDns><dn value="sys/chassis-1/blade-1"/><dn value="sys/rack-unit-1"/></outDns></configFindDnsByClassId>`
+		case strings.Contains(body, "configResolveDn"):
+			return `<configResolveDn dn="sys/chassis-1/blade-1" cookie="abc" response="yes"><outConfig><computeBlade dn="sys/chassis-1/blade-1" model="UCSB-B200-M4" serial="FCH1234" uuid="1b4e28ba-2fa1-11d2-883f-0016d3cca427" serverId="1/1"/></outConfig></configResolveDn>`
+		}
+		return `<configResolveClass cookie="abc" response="yes" classId="topSystem"><outConfigs><topSystem dn="sys" name="UCS-A"/></outConfigs></configResolveClass>`
+	})
+	defer server.Close()
+	client := New(server.URL, "", "")
+	Convey("Should list DNs by class", t, func() {
+		dns, err := client.FindDnsByClassID("computeItem")
+		So(err, ShouldBeNil)
+		So(dns, ShouldResemble, []string{"sys/chassis-1/blade-1", "sys/rack-unit-1"})
+	})
+	Convey("Should resolve a DN to a server", t, func() {
+		configs, err := client.ResolveDn("sys/chassis-1/blade-1", false)
+		So(err, ShouldBeNil)
+		So(configs.Servers()[0].Serial, ShouldEqual, "FCH1234")
+		So(configs.Servers()[0].ServerID, ShouldEqual, "1/1")
+	})
+	Convey("Should resolve several classes in one call", t, func() {
+		configs, err := client.ResolveClasses([]string{"computeBlade", "computeRackUnit"}, false)
+		So(err, ShouldBeNil)
+		So(len(configs.Servers()), ShouldEqual, 2)
+		So(configs.RackUnits[0].Serial, ShouldEqual, "WZP5678")
+	})
+	Convey("Should resolve a class", t, func() {
+		configs, err := client.ResolveClass("topSystem", false)
+		So(err, ShouldBeNil)
+		So(configs.TopSystems[0].Name, ShouldEqual, "UCS-A")
+	})
+}
@@ -0,0 +1,91 @@
+package ucsm
+
+import (
+	"encoding/xml"
+)
+
+type configResolveClass struct {
+	XMLName        xml.Name `xml:"configResolveClass"`
+	Cookie         string   `xml:"cookie,attr"`
+	ClassID        string   `xml:"classId,attr"`
+	InHierarchical bool     `xml:"inHierarchical,attr"`
+}
+
+type configResolveDn struct {
+	XMLName        xml.Name `xml:"configResolveDn"`
+	Cookie         string   `xml:"cookie,attr"`
+	Dn             string   `xml:"dn,attr"`
+	InHierarchical bool     `xml:"inHierarchical,attr"`
+}
+
+type classID struct {
+	Value string `xml:"value,attr"`
+}
+
+type configResolveClasses struct {
+	XMLName        xml.Name  `xml:"configResolveClasses"`
+	Cookie         string    `xml:"cookie,attr"`
+	InHierarchical bool      `xml:"inHierarchical,attr"`
+	InIds          []classID `xml:"inIds>Id"`
+}
+
+type configFindDnsByClassID struct {
+	XMLName xml.Name `xml:"configFindDnsByClassId"`
+	Cookie  string   `xml:"cookie,attr"`
+	ClassID string   `xml:"classId,attr"`
+}
+
+type configsResponse struct {
+	Configs Configs `xml:"outConfigs"`
+}
+
+type configResponse struct {
+	Config Configs `xml:"outConfig"`
+}
+
+type dnsResponse struct {
+	Dns []struct {
+		Value string `xml:"value,attr"`
+	} `xml:"outDns>dn"`
+}
+
+func (c *Client) ResolveClass(classID string, hierarchical bool) (*Configs, error) {
+	var response configsResponse
+	if err := c.call(configResolveClass{Cookie: c.Cookie(), ClassID: classID, InHierarchical: hierarchical}, &response, true); err != nil {
+		return nil, err
+	}
+	return &response.Configs, nil
+}
+
+// ResolveClasses returns every object of the given classes in a single call.
+func (c *Client) ResolveClasses(classIDs []string, hierarchical bool) (*Configs, error) {
+	request := configResolveClasses{Cookie: c.Cookie(), InHierarchical: hierarchical}
+	for i := 0; i < len(classIDs); i++ {
+		request.InIds = append(request.InIds, classID{Value: classIDs[i]})
+	}
+	var response configsResponse
+	if err := c.call(request, &response, true); err != nil {
+		return nil, err
+	}
+	return &response.Configs, nil
+}
+
+func (c *Client) ResolveDn(dn string, hierarchical bool) (*Configs, error) {
+	var response configResponse
+	if err := c.call(configResolveDn{Cookie: c.Cookie(), Dn: dn, InHierarchical: hierarchical}, &response, true); err != nil {
+		return nil, err
+	}
+	return &response.Config, nil
+}
+
+func (c *Client) FindDnsByClassID(classID string) ([]string, error) {
+	var response dnsResponse
+	if err := c.call(configFindDnsByClassID{Cookie: c.Cookie(), ClassID: classID}, &response, true); err != nil {
+		return nil, err
+	}
+	dns := []string{}
+	for i := 0; i < len(response.Dns); i++ {
+		dns = append(dns, response.Dns[i].Value)
+	}
+	return dns, nil
+}
@@ -0,0 +1,28 @@
+package ucsm
+
+import (
+	"strconv"
+)
+
+// HTTPError is returned when UCS Manager responds with anything other than
+// 200 OK.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return "ucsm: " + e.URL + " returned status code " + strconv.Itoa(e.StatusCode)
+}
+
+// Error is returned when UCS Manager answers a method with an errorCode.
+type Error struct {
+	Method      string
+	Code        string
+	Description string
+}
+
+func (e *Error) Error() string {
+	return "ucsm: " + e.Method + " failed with error " + e.Code + ": " + e.Description
+}
@@ -0,0 +1,99 @@
+package ucsm
+
+import (
+	"strings"
+)
+
+// Configs holds the managed objects returned by a configResolve method. Only
+// the classes this package knows about are decoded.
+type Configs struct {
+	TopSystems []TopSystem     `xml:"topSystem"`
+	Blades     []ComputeServer `xml:"computeBlade"`
+	RackUnits  []ComputeServer `xml:"computeRackUnit"`
+	Profiles   []LsServer      `xml:"lsServer"`
+	Processors []ProcessorUnit `xml:"processorUnit"`
+}
+
+// Servers returns every blade and rack unit in the configs.
+func (c *Configs) Servers() []ComputeServer {
+	servers := []ComputeServer{}
+	servers = append(servers, c.Blades...)
+	servers = append(servers, c.RackUnits...)
+	return servers
+}
+
+type TopSystem struct {
+	Dn      string `xml:"dn,attr"`
+	Name    string `xml:"name,attr"`
+	Address string `xml:"address,attr"`
+	Mode    string `xml:"mode,attr"`
+}
+
+// ComputeServer is a computeBlade or computeRackUnit.
+type ComputeServer struct {
+	Dn           string         `xml:"dn,attr"`
+	Model        string         `xml:"model,attr"`
+	Name         string         `xml:"name,attr"`
+	OriginalUUID string         `xml:"originalUuid,attr"`
+	PartNumber   string         `xml:"partNumber,attr"`
+	Serial       string         `xml:"serial,attr"`
+	UUID         string         `xml:"uuid,attr"`
+	ServerID     string         `xml:"serverId,attr"`
+	Descr        string         `xml:"descr,attr"`
+	AssignedToDn string         `xml:"assignedToDn,attr"`
+	Boards       []ComputeBoard `xml:"computeBoard"`
+}
+
+// ComputeBoard is the motherboard of a server. Its processors are only
+// decoded from hierarchical queries.
+type ComputeBoard struct {
+	Dn         string          `xml:"dn,attr"`
+	Processors []ProcessorUnit `xml:"processorUnit"`
+}
+
+// ProcessorUnit is a single CPU socket. Counts are left as strings, as UCS
+// Manager reports "unspecified" for empty sockets.
+type ProcessorUnit struct {
+	Dn                string `xml:"dn,attr"`
+	ID                string `xml:"id,attr"`
+	Model             string `xml:"model,attr"`
+	Arch              string `xml:"arch,attr"`
+	Cores             string `xml:"cores,attr"`
+	CoresEnabled      string `xml:"coresEnabled,attr"`
+	Threads           string `xml:"threads,attr"`
+	Speed             string `xml:"speed,attr"`
+	SocketDesignation string `xml:"socketDesignation,attr"`
+	Presence          string `xml:"presence,attr"`
+}
+
+// ServerDn returns the DN of the server the processor is fitted to.
+func (p ProcessorUnit) ServerDn() string {
+	if i := strings.Index(p.Dn, "/board"); i > -1 {
+		return p.Dn[:i]
+	}
+	return ""
+}
+
+// Equipped reports whether the socket has a processor fitted.
+func (p ProcessorUnit) Equipped() bool {
+	return p.Presence == "" || strings.HasPrefix(p.Presence, "equipped")
+}
+
+// LsServer is a service profile. PnDn is the physical server it is associated
+// with.
+type LsServer struct {
+	Dn         string `xml:"dn,attr"`
+	Name       string `xml:"name,attr"`
+	PnDn       string `xml:"pnDn,attr"`
+	AssocState string `xml:"assocState,attr"`
+	UUID       string `xml:"uuid,attr"`
+}
+
+// Org returns the organisation path of the service profile, such as
+// org-root/org-Finance.
+func (p LsServer) Org() string {
+	if i := strings.LastIndex(p.Dn, "/ls-"); i > -1 {
+		return p.Dn[:i]
+	}
+	return ""
+}
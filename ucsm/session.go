@@ -0,0 +1,126 @@
+package ucsm
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRefreshPeriod = 600
+	minimumRefreshWait   = 10 * time.Second
+)
+
+// IsInvalidCookie reports whether err is UCS Manager rejecting the session
+// cookie, either because it expired or because it was never valid.
+func IsInvalidCookie(err error) bool {
+	ucsErr, ok := err.(*Error)
+	if !ok {
+		return false
+	}
+	return ucsErr.Code == "552" || strings.Contains(strings.ToLower(ucsErr.Description), "cookie")
+}
+
+// SessionManager keeps a logged in Client alive. It refreshes the cookie at
+// half of outRefreshPeriod, and Do logs in again when a call is rejected
+// because of an invalid cookie.
+type SessionManager struct {
+	Client    *Client
+	OnRefresh func(err error)
+	OnRelogin func(err error)
+	lock      sync.Mutex
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func NewSessionManager(client *Client) *SessionManager {
+	return &SessionManager{Client: client}
+}
+
+func (s *SessionManager) interval() time.Duration {
+	period := s.Client.RefreshPeriod()
+	if period <= 0 {
+		period = defaultRefreshPeriod
+	}
+	wait := time.Duration(period) * time.Second / 2
+	if wait < minimumRefreshWait {
+		wait = minimumRefreshWait
+	}
+	return wait
+}
+
+// Start refreshes the session in the background until Stop is called.
+func (s *SessionManager) Start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.run(s.stop, s.done)
+}
+
+func (s *SessionManager) run(stop chan struct{}, done chan struct{}) {
+	defer close(done)
+	for {
+		timer := time.NewTimer(s.interval())
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.refresh()
+		}
+	}
+}
+
+func (s *SessionManager) refresh() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err := s.Client.Refresh()
+	if s.OnRefresh != nil {
+		s.OnRefresh(err)
+	}
+	if err != nil {
+		_, err = s.Client.Login()
+		if s.OnRelogin != nil {
+			s.OnRelogin(err)
+		}
+	}
+}
+
+// Stop ends the background refresh. It does not log out.
+func (s *SessionManager) Stop() {
+	s.lock.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.lock.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// Do runs call, and if it fails with an invalid cookie logs in again and runs
+// it once more. Concurrent failures with the same cookie only log in once.
+func (s *SessionManager) Do(call func() error) error {
+	cookie := s.Client.Cookie()
+	err := call()
+	if !IsInvalidCookie(err) {
+		return err
+	}
+	s.lock.Lock()
+	if s.Client.Cookie() == cookie {
+		_, loginErr := s.Client.Login()
+		if s.OnRelogin != nil {
+			s.OnRelogin(loginErr)
+		}
+		if loginErr != nil {
+			s.lock.Unlock()
+			return loginErr
+		}
+	}
+	s.lock.Unlock()
+	return call()
+}
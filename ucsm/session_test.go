@@ -0,0 +1,58 @@
+package ucsm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test_IsInvalidCookie(t *testing.T) {
+	Convey("Should detect an expired cookie", t, func() {
+		So(IsInvalidCookie(&Error{Method: "configResolveDn", Code: "552", Description: "Authorization required"}), ShouldBeTrue)
+	})
+	Convey("Should ignore other errors", t, func() {
+		So(IsInvalidCookie(&Error{Method: "aaaLogin", Code: "551", Description: "Authentication failed"}), ShouldBeFalse)
+		So(IsInvalidCookie(errors.New("connection refused")), ShouldBeFalse)
+		So(IsInvalidCookie(nil), ShouldBeFalse)
+	})
+}
+
+func Test_SessionDo(t *testing.T) {
+	logins := 0
+	server := newTestServer(func(body string) string {
+		if strings.Contains(body, "aaaLogin") {
+			logins++
+			return `<aaaLogin response="yes" outCookie="new" outRefreshPeriod="600"></aaaLogin>`
+		}
+		if strings.Contains(body, `cookie="new"`) {
+			return `<configResolveClass response="yes"><outConfigs><topSystem name="UCS-A"/></outConfigs></configResolveClass>`
+		}
+		return `<configResolveClass response="yes" errorCode="552" errorDescr="Authorization required"></configResolveClass>`
+	})
+	defer server.Close()
+	Convey("Should log in again and retry on an invalid cookie", t, func() {
+		client := New(server.URL, "admin", "secret")
+		client.setSession("expired", "", 600)
+		session := NewSessionManager(client)
+		var configs *Configs
+		err := session.Do(func() error {
+			var err error
+			configs, err = client.ResolveClass("topSystem", false)
+			return err
+		})
+		So(err, ShouldBeNil)
+		So(logins, ShouldEqual, 1)
+		So(client.Cookie(), ShouldEqual, "new")
+		So(configs.TopSystems[0].Name, ShouldEqual, "UCS-A")
+	})
+}
+
+func Test_SessionInterval(t *testing.T) {
+	Convey("Should refresh at half the refresh period", t, func() {
+		client := New("ucs", "", "")
+		client.setSession("abc", "", 600)
+		So(NewSessionManager(client).interval().Seconds(), ShouldEqual, 300)
+	})
+}
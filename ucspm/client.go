@@ -0,0 +1,178 @@
+// Package ucspm is a client for the JSON-RPC routers and performance API of
+// Cisco UCS Performance Manager.
+package ucspm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"../transport"
+)
+
+// SendFunc sends a single request and returns the status code and body.
+type SendFunc func(url string, method string, body string, headers map[string]string) (int, string, error)
+
+// RecordFunc is called after every attempt at a request, whether or not it
+// succeeded. Attempts are numbered from 1.
+type RecordFunc func(url string, body string, headers map[string]string, response string, code int, attempt int, err error)
+
+// Client talks to a single UCS Performance Manager instance. It is safe to use
+// from several goroutines at once. Every call is a query, so all of them are
+// retried according to Retry.
+type Client struct {
+	URL        string
+	Username   string
+	Password   string
+	HTTPClient *http.Client
+	Send       SendFunc
+	Record     RecordFunc
+	Retry      transport.RetryPolicy
+	Breaker    *transport.Breaker
+	tid        int64
+}
+
+// Router identifies a JSON-RPC router by its action name and endpoint.
+type Router struct {
+	Action   string
+	Endpoint string
+}
+
+// The routers used by this package.
+var (
+	DeviceRouter = Router{Action: "DeviceRouter", Endpoint: "device_router"}
+	EventsRouter = Router{Action: "EventsRouter", Endpoint: "evconsole_router"}
+	ReportRouter = Router{Action: "ReportRouter", Endpoint: "report_router"}
+)
+
+type rpcRequest struct {
+	Action string        `json:"action"`
+	Method string        `json:"method"`
+	Data   []interface{} `json:"data"`
+	Tid    int           `json:"tid"`
+}
+
+type rpcResponse struct {
+	Action  string          `json:"action"`
+	Method  string          `json:"method"`
+	Tid     int             `json:"tid"`
+	Type    string          `json:"type"`
+	Message string          `json:"message"`
+	Result  json.RawMessage `json:"result"`
+}
+
+type rpcStatus struct {
+	Success *bool  `json:"success"`
+	Msg     string `json:"msg"`
+}
+
+// New returns a client for the instance at url, which may be a bare host name
+// or address, using basic authentication.
+func New(url string, username string, password string) *Client {
+	return &Client{URL: url, Username: username, Password: password}
+}
+
+// BaseURL returns the instance URL with a scheme and a trailing slash.
+func (c *Client) BaseURL() string {
+	url := strings.TrimSpace(c.URL)
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		url = "https://" + url
+	}
+	if !strings.HasSuffix(url, "/") {
+		url += "/"
+	}
+	return url
+}
+
+func (c *Client) nextTid() int {
+	return int(atomic.AddInt64(&c.tid, 1))
+}
+
+func (c *Client) headers() map[string]string {
+	headers := make(map[string]string)
+	headers["Content-type"] = "application/json"
+	headers["Accept-Charset"] = "utf-8"
+	headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password))
+	return headers
+}
+
+func (c *Client) send(url string, body string, headers map[string]string) (int, string, error) {
+	if c.Send != nil {
+		return c.Send(url, "POST", body, headers)
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewBufferString(body))
+	if err != nil {
+		return 0, "", err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(data), err
+}
+
+func (c *Client) post(path string, payload interface{}) (string, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	url := c.BaseURL() + path
+	headers := c.headers()
+	response := ""
+	code, err := c.Retry.Run(c.Breaker, url, true, func(attempt int) (int, error) {
+		code, reply, err := c.send(url, string(body), headers)
+		response = reply
+		if c.Record != nil {
+			c.Record(url, string(body), headers, reply, code, attempt, err)
+		}
+		return code, err
+	})
+	if err != nil {
+		return "", err
+	}
+	if code != http.StatusOK {
+		return "", &HTTPError{URL: url, StatusCode: code, Body: response}
+	}
+	return response, nil
+}
+
+// Call runs a single router method and decodes its result into result, which
+// may be nil if the result is not needed.
+func (c *Client) Call(router Router, method string, data interface{}, result interface{}) error {
+	request := rpcRequest{Action: router.Action, Method: method, Data: []interface{}{data}, Tid: c.nextTid()}
+	response, err := c.post("zport/dmd/"+router.Endpoint, request)
+	if err != nil {
+		return err
+	}
+	var rpc rpcResponse
+	if err := json.Unmarshal([]byte(response), &rpc); err != nil {
+		return &RPCError{Action: router.Action, Method: method, Tid: request.Tid, Message: "invalid response: " + err.Error()}
+	}
+	if rpc.Type == "exception" {
+		return &RPCError{Action: router.Action, Method: method, Tid: request.Tid, Message: rpc.Message}
+	}
+	var status rpcStatus
+	if err := json.Unmarshal(rpc.Result, &status); err == nil && status.Success != nil && !*status.Success {
+		return &RPCError{Action: router.Action, Method: method, Tid: request.Tid, Message: status.Msg}
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(rpc.Result, result); err != nil {
+		return &RPCError{Action: router.Action, Method: method, Tid: request.Tid, Message: "invalid result: " + err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package ucspm
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newTestServer(handler func(req rpcRequest) string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var req rpcRequest
+		json.Unmarshal(body, &req)
+		w.Write([]byte(handler(req)))
+	}))
+}
+
+func Test_BaseURL(t *testing.T) {
+	Convey("Should add a scheme and trailing slash", t, func() {
+		So(New("10.0.0.1", "", "").BaseURL(), ShouldEqual, "https://10.0.0.1/")
+	})
+	Convey("Should keep an existing scheme", t, func() {
+		So(New("http://ucspm.local/", "", "").BaseURL(), ShouldEqual, "http://ucspm.local/")
+	})
+}
+
+func Test_Tids(t *testing.T) {
+	Convey("Should generate increasing tids", t, func() {
+		client := New("ucspm", "", "")
+		So(client.nextTid(), ShouldEqual, 1)
+		So(client.nextTid(), ShouldEqual, 2)
+	})
+}
+
+func Test_GetDevices(t *testing.T) {
+	server := newTestServer(func(req rpcRequest) string {
+		return `{"action":"DeviceRouter","method":"getDevices","tid":1,"type":"rpc","result":{"success":true,"totalCount":1,"devices":[{"uid":"/zport/dmd/Devices/vSphere/devices/vc1","name":"vc1","osModel":{"name":"VMware vCenter Server"}}]}}`
+	})
+	defer server.Close()
+	Convey("Should decode devices", t, func() {
+		result, err := New(server.URL, "admin", "zenoss").GetDevices(GetDevicesParams{UID: "/zport/dmd/Devices"})
+		So(err, ShouldBeNil)
+		So(result.TotalCount, ShouldEqual, 1)
+		So(result.Devices[0].OSModel.Name, ShouldEqual, "VMware vCenter Server")
+	})
+}
+
+func Test_Errors(t *testing.T) {
+	exception := newTestServer(func(req rpcRequest) string {
+		return `{"type":"exception","message":"Not found","tid":1}`
+	})
+	defer exception.Close()
+	failed := newTestServer(func(req rpcRequest) string {
+		return `{"type":"rpc","tid":1,"result":{"success":false,"msg":"Permission denied"}}`
+	})
+	defer failed.Close()
+	status := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer status.Close()
+	Convey("Should return an RPCError for exceptions", t, func() {
+		_, err := New(exception.URL, "", "").GetInfo("/zport/dmd/Devices/x", nil)
+		_, ok := err.(*RPCError)
+		So(ok, ShouldBeTrue)
+		So(err.Error(), ShouldContainSubstring, "Not found")
+	})
+	Convey("Should return an RPCError for unsuccessful results", t, func() {
+		_, err := New(failed.URL, "", "").QueryEvents(EventQueryParams{})
+		_, ok := err.(*RPCError)
+		So(ok, ShouldBeTrue)
+		So(err.Error(), ShouldContainSubstring, "Permission denied")
+	})
+	Convey("Should return an HTTPError for bad status codes", t, func() {
+		_, err := New(status.URL, "", "").GetReportTree("")
+		httpErr, ok := err.(*HTTPError)
+		So(ok, ShouldBeTrue)
+		So(httpErr.StatusCode, ShouldEqual, http.StatusUnauthorized)
+	})
+}
+
+func Test_QueryPerformance(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"results":[{"metric":"Usage","datapoints":[{"timestamp":1500000000,"value":12.5}]}]}`))
+	}))
+	defer server.Close()
+	Convey("Should decode performance series", t, func() {
+		result, err := New(server.URL, "", "").QueryPerformance(PerformanceQuery{Start: 1, End: 2})
+		So(err, ShouldBeNil)
+		So(result.Results[0].Metric, ShouldEqual, "Usage")
+		So(result.Results[0].Datapoints[0].Value, ShouldEqual, 12.5)
+	})
+}
@@ -0,0 +1,118 @@
+package ucspm
+
+// Reference points at another object, such as the parent device of a
+// component.
+type Reference struct {
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+}
+
+// Device is a single row returned by DeviceRouter.getDevices.
+type Device struct {
+	UID             string      `json:"uid"`
+	Name            string      `json:"name"`
+	IPAddress       interface{} `json:"ipAddress"`
+	ProductionState int         `json:"productionState"`
+	PythonClass     string      `json:"pythonClass"`
+	OSModel         *Reference  `json:"osModel"`
+	HWModel         *Reference  `json:"hwModel"`
+}
+
+// GetDevicesParams are the arguments of DeviceRouter.getDevices.
+type GetDevicesParams struct {
+	UID    string                 `json:"uid,omitempty"`
+	Start  int                    `json:"start"`
+	Limit  int                    `json:"limit,omitempty"`
+	Sort   string                 `json:"sort,omitempty"`
+	Dir    string                 `json:"dir,omitempty"`
+	Keys   []string               `json:"keys,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// GetDevicesResult is the result of DeviceRouter.getDevices.
+type GetDevicesResult struct {
+	TotalCount int      `json:"totalCount"`
+	Hash       string   `json:"hash"`
+	Devices    []Device `json:"devices"`
+}
+
+// DeviceInfo is the subset of DeviceRouter.getInfo data used for matching.
+type DeviceInfo struct {
+	UID               string     `json:"uid"`
+	ID                string     `json:"id"`
+	Name              string     `json:"name"`
+	Hostname          string     `json:"hostname"`
+	HardwareModel     string     `json:"hardwareModel"`
+	HardwareUUID      string     `json:"hardwareUUID"`
+	SerialNumber      string     `json:"serialNumber"`
+	HypervisorVersion string     `json:"hypervisorVersion"`
+	Device            *Reference `json:"device"`
+}
+
+type getInfoParams struct {
+	UID  string   `json:"uid"`
+	Keys []string `json:"keys,omitempty"`
+}
+
+type getInfoResult struct {
+	Data DeviceInfo `json:"data"`
+}
+
+// Component is a single row returned by DeviceRouter.getComponents.
+type Component struct {
+	UID               string     `json:"uid"`
+	ID                string     `json:"id"`
+	Name              string     `json:"name"`
+	Title             string     `json:"title"`
+	MetaType          string     `json:"meta_type"`
+	HardwareUUID      string     `json:"hardwareUUID"`
+	HypervisorVersion string     `json:"hypervisorVersion"`
+	Device            *Reference `json:"device"`
+}
+
+// GetComponentsParams are the arguments of DeviceRouter.getComponents.
+type GetComponentsParams struct {
+	UID      string   `json:"uid"`
+	MetaType string   `json:"meta_type,omitempty"`
+	Keys     []string `json:"keys,omitempty"`
+	Start    int      `json:"start"`
+	Limit    int      `json:"limit,omitempty"`
+	Sort     string   `json:"sort,omitempty"`
+	Dir      string   `json:"dir,omitempty"`
+}
+
+// GetComponentsResult is the result of DeviceRouter.getComponents.
+type GetComponentsResult struct {
+	TotalCount int         `json:"totalCount"`
+	Hash       string      `json:"hash"`
+	Data       []Component `json:"data"`
+}
+
+// GetDevices returns one page of the devices under params.UID.
+func (c *Client) GetDevices(params GetDevicesParams) (*GetDevicesResult, error) {
+	var result GetDevicesResult
+	if err := c.Call(DeviceRouter, "getDevices", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetInfo returns the details of the object at uid. Only the named keys are
+// returned, or every key if keys is empty.
+func (c *Client) GetInfo(uid string, keys []string) (*DeviceInfo, error) {
+	var result getInfoResult
+	if err := c.Call(DeviceRouter, "getInfo", getInfoParams{UID: uid, Keys: keys}, &result); err != nil {
+		return nil, err
+	}
+	return &result.Data, nil
+}
+
+// GetComponents returns one page of the components of the device at
+// params.UID, optionally only those of params.MetaType.
+func (c *Client) GetComponents(params GetComponentsParams) (*GetComponentsResult, error) {
+	var result GetComponentsResult
+	if err := c.Call(DeviceRouter, "getComponents", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,32 @@
+package ucspm
+
+import (
+	"strconv"
+)
+
+// HTTPError is returned when UCS Performance Manager responds with anything
+// other than 200 OK.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+// Error reports the URL and the status code, but not the body.
+func (e *HTTPError) Error() string {
+	return "ucspm: " + e.URL + " returned status code " + strconv.Itoa(e.StatusCode)
+}
+
+// RPCError is returned when a router reports an exception or an unsuccessful
+// result.
+type RPCError struct {
+	Action  string
+	Method  string
+	Tid     int
+	Message string
+}
+
+// Error reports the router method, its transaction id and the message.
+func (e *RPCError) Error() string {
+	return "ucspm: " + e.Action + "." + e.Method + " (tid " + strconv.Itoa(e.Tid) + ") failed: " + e.Message
+}
@@ -0,0 +1,41 @@
+package ucspm
+
+// Event is a single row returned by EventsRouter.query.
+type Event struct {
+	EvID       string      `json:"evid"`
+	Device     *Reference  `json:"device"`
+	Component  *Reference  `json:"component"`
+	EventClass *Reference  `json:"eventClass"`
+	Summary    string      `json:"summary"`
+	Severity   int         `json:"severity"`
+	EventState string      `json:"eventState"`
+	Count      int         `json:"count"`
+	FirstTime  interface{} `json:"firstTime"`
+	LastTime   interface{} `json:"lastTime"`
+}
+
+// EventQueryParams are the arguments of EventsRouter.query.
+type EventQueryParams struct {
+	UID    string                 `json:"uid,omitempty"`
+	Start  int                    `json:"start"`
+	Limit  int                    `json:"limit,omitempty"`
+	Sort   string                 `json:"sort,omitempty"`
+	Dir    string                 `json:"dir,omitempty"`
+	Keys   []string               `json:"keys,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// EventQueryResult is the result of EventsRouter.query.
+type EventQueryResult struct {
+	TotalCount int     `json:"totalCount"`
+	Events     []Event `json:"events"`
+}
+
+// QueryEvents returns one page of the events matching params.
+func (c *Client) QueryEvents(params EventQueryParams) (*EventQueryResult, error) {
+	var result EventQueryResult
+	if err := c.Call(EventsRouter, "query", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
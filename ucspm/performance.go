@@ -0,0 +1,84 @@
+package ucspm
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// PerformanceMetric is one metric or expression of a performance query.
+// Expressions only need Name and Expression set.
+type PerformanceMetric struct {
+	Metric      string                 `json:"metric,omitempty"`
+	Rate        bool                   `json:"rate"`
+	RateOptions map[string]interface{} `json:"rateOptions,omitempty"`
+	Aggregator  string                 `json:"aggregator,omitempty"`
+	Tags        map[string][]string    `json:"tags,omitempty"`
+	Name        string                 `json:"name"`
+	Expression  string                 `json:"expression,omitempty"`
+	Emit        bool                   `json:"emit"`
+}
+
+// PerformanceQuery is the body of a request to api/performance/query/.
+type PerformanceQuery struct {
+	Start      int64               `json:"start"`
+	End        int64               `json:"end"`
+	Series     bool                `json:"series"`
+	Downsample string              `json:"downsample,omitempty"`
+	Tags       map[string][]string `json:"tags"`
+	ReturnSet  string              `json:"returnset,omitempty"`
+	Metrics    []PerformanceMetric `json:"metrics"`
+}
+
+// Datapoint is a single sample of a performance series.
+type Datapoint struct {
+	Timestamp float64 `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+// PerformanceSeries is one series of a performance query response. Metric
+// holds the name given to the metric or expression in the query.
+type PerformanceSeries struct {
+	Metric     string              `json:"metric"`
+	Tags       map[string][]string `json:"tags"`
+	Datapoints []Datapoint         `json:"datapoints"`
+}
+
+// PerformanceResult is the response to a performance query.
+type PerformanceResult struct {
+	ClientID string              `json:"clientId"`
+	Series   bool                `json:"series"`
+	Source   string              `json:"source"`
+	Start    int64               `json:"startTimeActual"`
+	End      int64               `json:"endTimeActual"`
+	Results  []PerformanceSeries `json:"results"`
+	Status   *QueryStatus        `json:"status"`
+}
+
+// QueryStatus reports whether the performance query could be run.
+type QueryStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// QueryPerformance runs a performance query. A query that UCS Performance
+// Manager reports as failed is returned as an error.
+func (c *Client) QueryPerformance(query PerformanceQuery) (*PerformanceResult, error) {
+	if query.Tags == nil {
+		query.Tags = make(map[string][]string)
+	}
+	response, err := c.post("api/performance/query/", query)
+	if err != nil {
+		return nil, err
+	}
+	if response == "" {
+		return nil, errors.New("ucspm: empty performance response")
+	}
+	var result PerformanceResult
+	if err := json.Unmarshal([]byte(response), &result); err != nil {
+		return nil, err
+	}
+	if result.Status != nil && result.Status.Status == "ERROR" {
+		return nil, errors.New("ucspm: performance query failed: " + result.Status.Message)
+	}
+	return &result, nil
+}
@@ -0,0 +1,27 @@
+package ucspm
+
+// ReportNode is a node of the report tree returned by ReportRouter.getTree.
+type ReportNode struct {
+	ID       string       `json:"id"`
+	UID      string       `json:"uid"`
+	Text     interface{}  `json:"text"`
+	Leaf     bool         `json:"leaf"`
+	Children []ReportNode `json:"children"`
+}
+
+type getTreeParams struct {
+	ID string `json:"id"`
+}
+
+// GetReportTree returns the reports under the node id, or under the top of
+// the report tree if id is empty.
+func (c *Client) GetReportTree(id string) ([]ReportNode, error) {
+	if id == "" {
+		id = "/zport/dmd/Reports"
+	}
+	var result []ReportNode
+	if err := c.Call(ReportRouter, "getTree", getTreeParams{ID: id}, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}